@@ -0,0 +1,413 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"golang_course/interviews/data_types/endian"
+)
+
+// go test -v homework_test.go swap_test.go codec_test.go
+
+var (
+	ErrNotStruct       = errors.New("binary: value is not a struct")
+	ErrVariableSize    = errors.New("binary: variable-size field")
+	ErrMisalignedField = errors.New("binary: misaligned field")
+	ErrShortBuffer     = errors.New("binary: buffer too short")
+	ErrInvalidTag      = errors.New("binary: invalid tag")
+)
+
+// Теги полей: `binary:"big"`, `binary:"little"` - порядок байт поля,
+// `binary:"pad=N"` - N нулевых байт после поля, `binary:"unaligned"` - не проверять
+// выравнивание поля, `binary:"-"` - пропустить поле. Опции перечисляются через запятую.
+type binaryOptions struct {
+	order     endian.ByteOrder
+	pad       int
+	unaligned bool
+	skip      bool
+}
+
+func parseBinaryTag(field reflect.StructField, order endian.ByteOrder) (binaryOptions, error) {
+	options := binaryOptions{order: order}
+	tag := field.Tag.Get("binary")
+	if tag == "" {
+		return options, nil
+	}
+	if tag == "-" {
+		options.skip = true
+		return options, nil
+	}
+
+	for _, part := range strings.Split(tag, ",") {
+		switch {
+		case part == "big":
+			options.order = endian.BigEndian
+		case part == "little":
+			options.order = endian.LittleEndian
+		case part == "unaligned":
+			options.unaligned = true
+		case strings.HasPrefix(part, "pad="):
+			pad, err := strconv.Atoi(strings.TrimPrefix(part, "pad="))
+			if err != nil || pad < 0 {
+				return options, fmt.Errorf("%w: field %s: bad padding %q", ErrInvalidTag, field.Name, part)
+			}
+			options.pad = pad
+		default:
+			return options, fmt.Errorf("%w: field %s: unknown option %q", ErrInvalidTag, field.Name, part)
+		}
+	}
+
+	return options, nil
+}
+
+// codec - обход полей структуры; без буфера только считает размер и проверяет раскладку
+type codec struct {
+	buffer []byte
+	offset int
+	decode bool
+}
+
+func (c *codec) value(v reflect.Value, order endian.ByteOrder, path string, checkAlign bool) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		vType := v.Type()
+		for i := 0; i < vType.NumField(); i++ {
+			field := vType.Field(i)
+			options, err := parseBinaryTag(field, order)
+			if err != nil {
+				return err
+			}
+			if options.skip {
+				continue
+			}
+
+			fieldPath := field.Name
+			if path != "" {
+				fieldPath = path + "." + field.Name
+			}
+
+			if field.Name == "_" {
+				// blank поле - явное заполнение своего размера, как в encoding/binary
+				c.offset += int(field.Type.Size())
+			} else if !field.IsExported() {
+				return fmt.Errorf("binary: field %s is unexported", fieldPath)
+			} else if err := c.value(v.Field(i), options.order, fieldPath, checkAlign && !options.unaligned); err != nil {
+				return err
+			}
+
+			c.offset += options.pad
+		}
+		return nil
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := c.value(v.Index(i), order, fmt.Sprintf("%s[%d]", path, i), checkAlign); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Bool,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return c.scalar(v, order, path, checkAlign)
+	case reflect.Int, reflect.Uint, reflect.Uintptr:
+		return fmt.Errorf("%w: field %s has platform-dependent size (%s), use sized type", ErrVariableSize, path, v.Type())
+	default:
+		return fmt.Errorf("%w: field %s has type %s", ErrVariableSize, path, v.Type())
+	}
+}
+
+func (c *codec) scalar(v reflect.Value, order endian.ByteOrder, path string, checkAlign bool) error {
+	size := int(v.Type().Size())
+	if align := v.Type().Align(); checkAlign && c.offset%align != 0 {
+		return fmt.Errorf("%w: field %s at offset %d is not aligned to %d bytes (add pad= to previous field)",
+			ErrMisalignedField, path, c.offset, align)
+	}
+
+	if c.buffer != nil {
+		b := c.buffer[c.offset : c.offset+size]
+		if c.decode {
+			setBits(v, getBits(b, order))
+		} else {
+			putBits(b, valueBits(v), order)
+		}
+	}
+
+	c.offset += size
+	return nil
+}
+
+func valueBits(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(v.Int())
+	case reflect.Float32:
+		return uint64(math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		return math.Float64bits(v.Float())
+	default:
+		return v.Uint()
+	}
+}
+
+func setBits(v reflect.Value, bits uint64) {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(bits != 0)
+	case reflect.Int8:
+		v.SetInt(int64(int8(bits)))
+	case reflect.Int16:
+		v.SetInt(int64(int16(bits)))
+	case reflect.Int32:
+		v.SetInt(int64(int32(bits)))
+	case reflect.Int64:
+		v.SetInt(int64(bits))
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(uint32(bits))))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(bits))
+	default:
+		v.SetUint(bits)
+	}
+}
+
+// putBits - записать значение в порядке платформы, развернув байты через ToLittleEndian,
+// если требуемый порядок отличается от порядка платформы
+func putBits(b []byte, bits uint64, order endian.ByteOrder) {
+	swap := order != endian.HostOrder
+	switch len(b) {
+	case 1:
+		b[0] = byte(bits)
+	case 2:
+		value := uint16(bits)
+		if swap {
+			value = ToLittleEndian(value)
+		}
+		endian.HostOrder.PutUint16(b, value)
+	case 4:
+		value := uint32(bits)
+		if swap {
+			value = ToLittleEndian(value)
+		}
+		endian.HostOrder.PutUint32(b, value)
+	case 8:
+		if swap {
+			bits = ToLittleEndian(bits)
+		}
+		endian.HostOrder.PutUint64(b, bits)
+	}
+}
+
+func getBits(b []byte, order endian.ByteOrder) uint64 {
+	swap := order != endian.HostOrder
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		value := endian.HostOrder.Uint16(b)
+		if swap {
+			value = ToLittleEndian(value)
+		}
+		return uint64(value)
+	case 4:
+		value := endian.HostOrder.Uint32(b)
+		if swap {
+			value = ToLittleEndian(value)
+		}
+		return uint64(value)
+	default:
+		value := endian.HostOrder.Uint64(b)
+		if swap {
+			value = ToLittleEndian(value)
+		}
+		return value
+	}
+}
+
+func structValue(v any) (reflect.Value, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%w: got %T", ErrNotStruct, v)
+	}
+
+	return value, nil
+}
+
+// Size - размер структуры в бинарном представлении
+func Size(v any) (int, error) {
+	value, err := structValue(v)
+	if err != nil {
+		return 0, err
+	}
+
+	var c codec
+	if err := c.value(value, endian.HostOrder, "", true); err != nil {
+		return 0, err
+	}
+
+	return c.offset, nil
+}
+
+// Marshal - закодировать структуру фиксированного размера
+func Marshal(v any, order endian.ByteOrder) ([]byte, error) {
+	value, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := Size(v)
+	if err != nil {
+		return nil, err
+	}
+
+	c := codec{buffer: make([]byte, size)}
+	if err := c.value(value, order, "", true); err != nil {
+		return nil, err
+	}
+
+	return c.buffer, nil
+}
+
+// Unmarshal - раскодировать data в структуру, на которую указывает v
+func Unmarshal(data []byte, v any, order endian.ByteOrder) error {
+	pointer := reflect.ValueOf(v)
+	if pointer.Kind() != reflect.Pointer || pointer.IsNil() || pointer.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: need non-nil pointer to struct, got %T", ErrNotStruct, v)
+	}
+
+	size, err := Size(v)
+	if err != nil {
+		return err
+	}
+	if len(data) < size {
+		return fmt.Errorf("%w: need %d bytes, got %d", ErrShortBuffer, size, len(data))
+	}
+
+	c := codec{buffer: data[:size], decode: true}
+	return c.value(pointer.Elem(), order, "", true)
+}
+
+type wireHeader struct {
+	Magic   [4]byte
+	Version uint16 `binary:"little"`
+	Flags   uint8  `binary:"pad=1"`
+	Length  uint32 `binary:"pad=4"`
+	Offset  int64
+	Ratio   float32
+	Valid   bool `binary:"pad=3"`
+	Point   wirePoint
+}
+
+type wirePoint struct {
+	X, Y int16
+}
+
+func TestMarshal(t *testing.T) {
+	header := wireHeader{
+		Magic:   [4]byte{'D', 'G', 'O', '1'},
+		Version: 0x0102,
+		Flags:   0x7F,
+		Length:  0x0A0B0C0D,
+		Offset:  -2,
+		Ratio:   1.5,
+		Valid:   true,
+		Point:   wirePoint{X: 1, Y: -1},
+	}
+
+	data, err := Marshal(header, endian.BigEndian)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		'D', 'G', 'O', '1',
+		0x02, 0x01, // little endian override
+		0x7F, 0x00, // pad=1
+		0x0A, 0x0B, 0x0C, 0x0D,
+		0x00, 0x00, 0x00, 0x00, // pad=4
+		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFE,
+		0x3F, 0xC0, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00, // pad=3
+		0x00, 0x01, 0xFF, 0xFF,
+	}, data)
+
+	var decoded wireHeader
+	assert.NoError(t, Unmarshal(data, &decoded, endian.BigEndian))
+	assert.Equal(t, header, decoded)
+
+	data, err = Marshal(&header, endian.LittleEndian)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x0D, 0x0C, 0x0B, 0x0A}, data[8:12])
+
+	decoded = wireHeader{}
+	assert.NoError(t, Unmarshal(data, &decoded, endian.LittleEndian))
+	assert.Equal(t, header, decoded)
+}
+
+func TestMarshalErrors(t *testing.T) {
+	type misaligned struct {
+		Flag   bool
+		Number int32
+	}
+	type unaligned struct {
+		Flag   bool
+		Number int32 `binary:"unaligned"`
+	}
+	type variable struct {
+		Name string
+	}
+	type platform struct {
+		Count int
+	}
+	type nested struct {
+		Items [2]struct{ Values []byte }
+	}
+	type badTag struct {
+		Flag bool `binary:"pad=x"`
+	}
+
+	tests := map[string]struct {
+		value any
+		err   error
+	}{
+		"misaligned field":     {value: misaligned{}, err: ErrMisalignedField},
+		"variable-size field":  {value: variable{}, err: ErrVariableSize},
+		"platform-dependent":   {value: platform{}, err: ErrVariableSize},
+		"nested variable-size": {value: nested{}, err: ErrVariableSize},
+		"invalid tag":          {value: badTag{}, err: ErrInvalidTag},
+		"not a struct":         {value: 42, err: ErrNotStruct},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Marshal(test.value, endian.BigEndian)
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+
+	_, err := Marshal(nested{}, endian.BigEndian)
+	assert.ErrorContains(t, err, "Items[0].Values")
+
+	data, err := Marshal(unaligned{Flag: true, Number: 1}, endian.BigEndian)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x00, 0x00, 0x00, 0x01}, data)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var header wireHeader
+	assert.ErrorIs(t, Unmarshal(make([]byte, 4), &header, endian.BigEndian), ErrShortBuffer)
+	assert.ErrorIs(t, Unmarshal(make([]byte, 64), header, endian.BigEndian), ErrNotStruct)
+	assert.ErrorIs(t, Unmarshal(make([]byte, 64), (*wireHeader)(nil), endian.BigEndian), ErrNotStruct)
+}
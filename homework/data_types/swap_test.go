@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"golang_course/interviews/data_types/endian"
+)
+
+// go test -bench=. homework_test.go swap_test.go
+
+func TestSwapSlice(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	values16 := make([]uint16, 103)
+	values32 := make([]uint32, 103)
+	values64 := make([]int64, 103)
+	for i := range values16 {
+		values16[i] = uint16(random.Uint32())
+		values32[i] = random.Uint32()
+		values64[i] = random.Int63()
+	}
+
+	// подсрезы со смещением проверяют поштучную обработку невыровненного начала
+	for offset := 0; offset < 4; offset++ {
+		expected16 := make([]uint16, 0, len(values16))
+		for _, value := range values16[offset:] {
+			expected16 = append(expected16, ToLittleEndian(value))
+		}
+		actual16 := append([]uint16(nil), values16...)
+		endian.SwapSlice(actual16[offset:])
+		assert.Equal(t, expected16, actual16[offset:])
+
+		expected32 := make([]uint32, 0, len(values32))
+		for _, value := range values32[offset:] {
+			expected32 = append(expected32, ToLittleEndian(value))
+		}
+		actual32 := append([]uint32(nil), values32...)
+		endian.SwapSlice(actual32[offset:])
+		assert.Equal(t, expected32, actual32[offset:])
+	}
+
+	expected64 := make([]int64, 0, len(values64))
+	for _, value := range values64 {
+		expected64 = append(expected64, endian.Swap(value))
+	}
+	endian.SwapSlice(values64)
+	assert.Equal(t, expected64, values64)
+
+	floats := []float32{1.5, -2}
+	endian.SwapSlice(floats)
+	endian.SwapSlice(floats)
+	assert.Equal(t, []float32{1.5, -2}, floats)
+
+	endian.SwapSlice([]uint32{})
+	endian.SwapSlice([]int8{1, 2})
+}
+
+const benchmarkLength = 1 << 20
+
+func BenchmarkSwap(b *testing.B) {
+	values16 := make([]uint16, benchmarkLength)
+	values32 := make([]uint32, benchmarkLength)
+	values64 := make([]uint64, benchmarkLength)
+
+	b.Run("uint16/loop", func(b *testing.B) {
+		b.SetBytes(benchmarkLength * 2)
+		for i := 0; i < b.N; i++ {
+			for j := range values16 {
+				values16[j] = ToLittleEndian(values16[j])
+			}
+		}
+	})
+	b.Run("uint16/slice", func(b *testing.B) {
+		b.SetBytes(benchmarkLength * 2)
+		for i := 0; i < b.N; i++ {
+			endian.SwapSlice(values16)
+		}
+	})
+
+	b.Run("uint32/loop", func(b *testing.B) {
+		b.SetBytes(benchmarkLength * 4)
+		for i := 0; i < b.N; i++ {
+			for j := range values32 {
+				values32[j] = ToLittleEndian(values32[j])
+			}
+		}
+	})
+	b.Run("uint32/reverse_bytes", func(b *testing.B) {
+		b.SetBytes(benchmarkLength * 4)
+		for i := 0; i < b.N; i++ {
+			for j := range values32 {
+				values32[j] = bits.ReverseBytes32(values32[j])
+			}
+		}
+	})
+	b.Run("uint32/slice", func(b *testing.B) {
+		b.SetBytes(benchmarkLength * 4)
+		for i := 0; i < b.N; i++ {
+			endian.SwapSlice(values32)
+		}
+	})
+
+	b.Run("uint64/loop", func(b *testing.B) {
+		b.SetBytes(benchmarkLength * 8)
+		for i := 0; i < b.N; i++ {
+			for j := range values64 {
+				values64[j] = ToLittleEndian(values64[j])
+			}
+		}
+	})
+	b.Run("uint64/slice", func(b *testing.B) {
+		b.SetBytes(benchmarkLength * 8)
+		for i := 0; i < b.N; i++ {
+			endian.SwapSlice(values64)
+		}
+	})
+}
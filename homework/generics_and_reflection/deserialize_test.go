@@ -0,0 +1,546 @@
+package main
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go deserialize_test.go format_test.go plan_test.go marshaler_test.go stream_test.go
+
+var (
+	ErrInvalidTarget = errors.New("target must be a non-nil pointer to struct")
+	ErrMalformedLine = errors.New("malformed line")
+	ErrUnknownKey    = errors.New("unknown key")
+	ErrInvalidValue  = errors.New("invalid value")
+	ErrInvalidIndex  = errors.New("invalid slice index")
+)
+
+// LineError - ошибка разбора с номером строки (нумерация с 1)
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+type DeserializeOption func(*deserializer)
+
+// WithStrictKeys - считать ошибкой ключи, которым не соответствует ни одно поле
+func WithStrictKeys() DeserializeOption {
+	return func(d *deserializer) {
+		d.strict = true
+	}
+}
+
+type entry struct {
+	value string
+	line  int
+	used  bool
+}
+
+type deserializer struct {
+	entries map[string]*entry
+	strict  bool
+
+	// индекс префиксов строится один раз после чтения всех строк, чтобы
+	// hasKey и indexedLength не перебирали все ключи при каждом вызове
+	prefixes map[string]bool           // key, у которых есть вложенные key.*
+	indexes  map[string]map[int]*entry // key -> индекс N из key.N -> первая по строкам запись
+	negative map[string]string         // key -> первый по строкам ключ с отрицательным индексом
+}
+
+// Deserialize - разобрать текст в формате properties в структуру, на которую указывает out
+func Deserialize(data string, out any, options ...DeserializeOption) error {
+	return NewDecoder(strings.NewReader(data), options...).Decode(out)
+}
+
+// parseLine - разобрать логическую строку (без комментариев и с уже склеенными продолжениями)
+func (d *deserializer) parseLine(line string, number int) error {
+	rawKey, rawValue := splitKeyValue(line)
+
+	key, err := unescapeProperty(rawKey)
+	if err != nil {
+		return &LineError{Line: number, Err: err}
+	}
+	value, err := unescapeProperty(rawValue)
+	if err != nil {
+		return &LineError{Line: number, Err: err}
+	}
+
+	d.entries[key] = &entry{value: value, line: number}
+	return nil
+}
+
+func (d *deserializer) checkUnknown() error {
+	if !d.strict {
+		return nil
+	}
+
+	var unknown *entry
+	var unknownKey string
+	for key, e := range d.entries {
+		if !e.used && (unknown == nil || e.line < unknown.line) {
+			unknown, unknownKey = e, key
+		}
+	}
+
+	if unknown != nil {
+		return &LineError{Line: unknown.line, Err: fmt.Errorf("%w: %q", ErrUnknownKey, unknownKey)}
+	}
+
+	return nil
+}
+
+func (d *deserializer) decodeStruct(v reflect.Value, prefix string) error {
+	vType := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := vType.Field(i)
+
+		key, options := parseTag(field)
+		if key == "-" || !field.IsExported() {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		if hasOption(options, "inline") && isStructOrPointerToStruct(field.Type) {
+			if fieldValue.Kind() == reflect.Pointer && fieldValue.IsNil() {
+				fieldValue.Set(reflect.New(field.Type.Elem()))
+			}
+			if err := d.decodeStruct(reflect.Indirect(fieldValue), prefix); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if key == "" {
+			continue
+		}
+
+		fieldOpts := fieldOptions{
+			defaultValue: tagDefault(options),
+			quoted:       hasOption(options, "string"),
+		}
+		if hasOption(options, "required") && fieldOpts.defaultValue == nil && !d.hasKey(prefix+key) {
+			return fmt.Errorf("%w: %q", ErrRequiredField, prefix+key)
+		}
+
+		if err := d.decodeValue(fieldValue, prefix+key, fieldOpts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fieldOptions - опции тега, влияющие на разбор значения поля
+type fieldOptions struct {
+	defaultValue *string
+	quoted       bool
+}
+
+func (d *deserializer) decodeValue(v reflect.Value, key string, options fieldOptions) error {
+	if v.Type() == timeType || isUnmarshaler(v) {
+		return d.decodeScalar(v, key, options)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !d.hasKey(key) && options.defaultValue == nil {
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeValue(v.Elem(), key, options)
+	case reflect.Struct:
+		return d.decodeStruct(v, key+".")
+	case reflect.Slice:
+		length, err := d.indexedLength(key)
+		if err != nil {
+			return err
+		}
+		if length == 0 {
+			return nil
+		}
+		// новый срез: в заполненном заранее не остаются лишние и пропущенные элементы
+		v.Set(reflect.MakeSlice(v.Type(), length, length))
+		for i := 0; i < length; i++ {
+			if err := d.decodeValue(v.Index(i), key+"."+strconv.Itoa(i), fieldOptions{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := d.decodeValue(v.Index(i), key+"."+strconv.Itoa(i), fieldOptions{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return d.decodeScalar(v, key, options)
+	}
+}
+
+func (d *deserializer) decodeScalar(v reflect.Value, key string, options fieldOptions) error {
+	e, ok := d.entries[key]
+	if !ok {
+		if options.defaultValue == nil {
+			return nil
+		}
+		if err := parseValue(v, *options.defaultValue); err != nil {
+			return fmt.Errorf("default for key %q: %w", key, err)
+		}
+		return nil
+	}
+
+	e.used = true
+	value := e.value
+	if options.quoted && isQuotable(v.Type()) {
+		if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+			return &LineError{Line: e.line, Err: fmt.Errorf("key %q: %w: expected quoted value", key, ErrInvalidValue)}
+		}
+		value = value[1 : len(value)-1]
+	}
+
+	if err := parseValue(v, value); err != nil {
+		return &LineError{Line: e.line, Err: fmt.Errorf("key %q: %w", key, err)}
+	}
+
+	return nil
+}
+
+// buildIndex - проиндексировать все префиксы ключей, вызывается после чтения
+// всех строк; время линейно по суммарной длине ключей
+func (d *deserializer) buildIndex() {
+	d.prefixes = make(map[string]bool)
+	d.indexes = make(map[string]map[int]*entry)
+	d.negative = make(map[string]string)
+
+	for k, e := range d.entries {
+		for end := strings.IndexByte(k, '.'); end >= 0; {
+			parent := k[:end]
+			d.prefixes[parent] = true
+
+			rest := k[end+1:]
+			index, _, _ := strings.Cut(rest, ".")
+			d.addIndex(parent, index, k, e)
+
+			next := strings.IndexByte(rest, '.')
+			if next < 0 {
+				break
+			}
+			end += 1 + next
+		}
+	}
+}
+
+func (d *deserializer) addIndex(parent, index, key string, e *entry) {
+	n, err := strconv.Atoi(index)
+	if err != nil || strconv.Itoa(n) != index {
+		return // не индекс, в строгом режиме такой ключ будет неизвестным
+	}
+
+	if n < 0 {
+		if previous, ok := d.negative[parent]; !ok || e.line < d.entries[previous].line {
+			d.negative[parent] = key
+		}
+		return
+	}
+
+	indexes := d.indexes[parent]
+	if indexes == nil {
+		indexes = make(map[int]*entry)
+		d.indexes[parent] = indexes
+	}
+	if previous, ok := indexes[n]; !ok || e.line < previous.line {
+		indexes[n] = e
+	}
+}
+
+// hasKey - есть ли ключ или вложенные в него ключи (key.*)
+func (d *deserializer) hasKey(key string) bool {
+	_, ok := d.entries[key]
+	return ok || d.prefixes[key]
+}
+
+// maxMissingIndexes - сколько пропущенных индексов среза заполняется нулевыми
+// значениями; Serialize пропускает nil-указатели и пустые вложенные срезы, поэтому
+// пропуски допустимы, но большой индекс не должен выделять память под миллионы
+// несуществующих элементов
+const maxMissingIndexes = 1024
+
+// indexedLength - длина среза по ключам вида key.N
+func (d *deserializer) indexedLength(key string) (int, error) {
+	if k, ok := d.negative[key]; ok {
+		return 0, &LineError{Line: d.entries[k].line, Err: fmt.Errorf("key %q: %w: negative index", k, ErrInvalidIndex)}
+	}
+
+	indexes := d.indexes[key]
+	length := 0
+	for n := range indexes {
+		length = max(length, n+1)
+	}
+
+	if missing := length - len(indexes); missing > maxMissingIndexes {
+		err := fmt.Errorf("key %q: %w: %d indexes are missing", key+"."+strconv.Itoa(length-1), ErrInvalidIndex, missing)
+		return 0, &LineError{Line: indexes[length-1].line, Err: err}
+	}
+
+	return length, nil
+}
+
+func tagDefault(options []string) *string {
+	for _, opt := range options {
+		if value, found := strings.CutPrefix(opt, "default="); found {
+			return &value
+		}
+	}
+
+	return nil
+}
+
+func parseValue(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		switch unmarshaler := v.Addr().Interface().(type) {
+		case PropertiesUnmarshaler:
+			if err := unmarshaler.UnmarshalProperties(s); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+			}
+			return nil
+		case encoding.TextUnmarshaler:
+			if err := unmarshaler.UnmarshalText([]byte(s)); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+			}
+			return nil
+		}
+	}
+
+	switch v.Type() {
+	case timeType:
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		v.Set(reflect.ValueOf(parsed))
+		return nil
+	case durationType:
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		v.SetInt(int64(parsed))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		v.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		v.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		parsed, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		v.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		v.SetFloat(parsed)
+	default:
+		return fmt.Errorf("%w: unsupported type %s", ErrInvalidValue, v.Type())
+	}
+
+	return nil
+}
+
+type Config struct {
+	Host    string        `properties:"host,default=localhost"`
+	Port    uint16        `properties:"port,default=8080"`
+	Timeout time.Duration `properties:"timeout,default=5s"`
+	Debug   bool          `properties:"debug"`
+	Ratio   float32       `properties:"ratio"`
+	Address *Address      `properties:"address"`
+	Tags    []string      `properties:"tags"`
+}
+
+func TestDeserialization(t *testing.T) {
+	tests := map[string]struct {
+		data   string
+		result Config
+	}{
+		"test case with defaults": {
+			data:   "",
+			result: Config{Host: "localhost", Port: 8080, Timeout: 5 * time.Second},
+		},
+		"test case with fields": {
+			data: "# comment\nhost=example.com\nport=443\n\ntimeout=1m\ndebug=true\nratio=0.25\n" +
+				"address.city=Paris\ntags.1=backend\ntags.0=go\nunknown=value",
+			result: Config{
+				Host:    "example.com",
+				Port:    443,
+				Timeout: time.Minute,
+				Debug:   true,
+				Ratio:   0.25,
+				Address: &Address{City: "Paris"},
+				Tags:    []string{"go", "backend"},
+			},
+		},
+		"test case with gap in slice indexes": {
+			data:   "tags.1=backend\ntags.3=go",
+			result: Config{Host: "localhost", Port: 8080, Timeout: 5 * time.Second, Tags: []string{"", "backend", "", "go"}},
+		},
+		"test case with value containing separator": {
+			data:   "host=a=b",
+			result: Config{Host: "a=b", Port: 8080, Timeout: 5 * time.Second},
+		},
+		"test case with java properties syntax": {
+			data: "! comment\r\n  host : \\ example.\\\r\n    com\r\nport 9090\r\ntags.0 = caf\\u00e9\\n",
+			result: Config{
+				Host:    " example.com",
+				Port:    9090,
+				Timeout: 5 * time.Second,
+				Tags:    []string{"café\n"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var config Config
+			assert.NoError(t, Deserialize(test.data, &config))
+			assert.Equal(t, test.result, config)
+		})
+	}
+
+	// срез заменяется целиком, старые элементы не остаются в хвосте
+	config := Config{Tags: []string{"a", "b", "c"}}
+	assert.NoError(t, Deserialize("tags.0=x", &config))
+	assert.Equal(t, []string{"x"}, config.Tags)
+}
+
+func TestDeserializationErrors(t *testing.T) {
+	tests := map[string]struct {
+		data    string
+		options []DeserializeOption
+		err     error
+		line    int
+	}{
+		"test case with malformed line": {
+			data: "host=example.com\nport=\\u12",
+			err:  ErrMalformedLine,
+			line: 2,
+		},
+		"test case with invalid value": {
+			data: "host=example.com\n\nport=70000",
+			err:  ErrInvalidValue,
+			line: 3,
+		},
+		"test case with unknown key in strict mode": {
+			data:    "host=example.com\naddress.country=France",
+			options: []DeserializeOption{WithStrictKeys()},
+			err:     ErrUnknownKey,
+			line:    2,
+		},
+		"test case with huge slice index": {
+			data: "tags.0=go\ntags.999999999=x",
+			err:  ErrInvalidIndex,
+			line: 2,
+		},
+		"test case with negative slice index": {
+			data: "tags.-1=go",
+			err:  ErrInvalidIndex,
+			line: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var config Config
+			err := Deserialize(test.data, &config, test.options...)
+			assert.ErrorIs(t, err, test.err)
+
+			var lineErr *LineError
+			assert.ErrorAs(t, err, &lineErr)
+			assert.Equal(t, test.line, lineErr.Line)
+		})
+	}
+
+	var config Config
+	assert.ErrorIs(t, Deserialize("", config), ErrInvalidTarget)
+	assert.ErrorIs(t, Deserialize("", (*Config)(nil)), ErrInvalidTarget)
+}
+
+func TestRoundTrip(t *testing.T) {
+	property := func(name string, age int, married bool, id uint64, rating float64, tags []string, city string) bool {
+		if len(tags) == 0 {
+			tags = nil
+		}
+
+		employee := Employee{ID: id, Rating: rating, Address: Address{City: city}, Tags: tags}
+		var decodedEmployee Employee
+		if err := Deserialize(Serialize(employee), &decodedEmployee, WithStrictKeys()); err != nil {
+			t.Log(err)
+			return false
+		}
+
+		person := Person{Name: name, Age: age, Married: married}
+		var decodedPerson Person
+		if err := Deserialize(Serialize(person), &decodedPerson, WithStrictKeys()); err != nil {
+			t.Log(err)
+			return false
+		}
+
+		return reflect.DeepEqual(employee, decodedEmployee) && person == decodedPerson
+	}
+
+	assert.NoError(t, quick.Check(property, nil))
+}
+
+type Route struct {
+	Stops  []*Address `properties:"stops"`
+	Groups [][]string `properties:"groups"`
+}
+
+func TestRoundTripWithSkippedElements(t *testing.T) {
+	// nil-указатели и пустые вложенные срезы не сериализуются и возвращаются нулевыми
+	route := Route{
+		Stops:  []*Address{nil, {City: "Rome"}, nil},
+		Groups: [][]string{nil, {"a"}},
+	}
+
+	data := Serialize(route)
+	assert.Equal(t, "stops.1.city=Rome\ngroups.1.0=a", data)
+
+	var decoded Route
+	assert.NoError(t, Deserialize(data, &decoded, WithStrictKeys()))
+	assert.Equal(t, Route{Stops: []*Address{nil, {City: "Rome"}}, Groups: route.Groups}, decoded)
+}
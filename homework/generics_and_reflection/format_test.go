@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go deserialize_test.go format_test.go plan_test.go marshaler_test.go stream_test.go
+
+// Формат соответствует java.util.Properties: экранирование через \, \uXXXX для
+// символов вне ASCII, продолжение строки через \ в конце, комментарии # и !,
+// разделители =, : или пробельные символы.
+
+const hexDigits = "0123456789ABCDEF"
+
+func escapeKey(key string) string {
+	return escapeProperty(key, true)
+}
+
+func escapeValue(value string) string {
+	return escapeProperty(value, false)
+}
+
+func escapeProperty(s string, isKey bool) string {
+	if !needsEscape(s, isKey) {
+		return s
+	}
+
+	return string(appendEscaped(make([]byte, 0, len(s)+8), s, isKey))
+}
+
+func needsEscape(s string, isKey bool) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c > 0x7E || c == '\\' || (c == ' ' && (isKey || i == 0)) {
+			return true
+		}
+		if isKey && (c == '=' || c == ':' || c == '#' || c == '!') {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendEscaped - дописать экранированную строку в буфер
+func appendEscaped(buf []byte, s string, isKey bool) []byte {
+	if !needsEscape(s, isKey) {
+		return append(buf, s...)
+	}
+
+	for idx, r := range s {
+		switch r {
+		case '\\':
+			buf = append(buf, `\\`...)
+		case '\t':
+			buf = append(buf, `\t`...)
+		case '\n':
+			buf = append(buf, `\n`...)
+		case '\r':
+			buf = append(buf, `\r`...)
+		case '\f':
+			buf = append(buf, `\f`...)
+		case ' ':
+			// в значении пробелы значимы только в начале - там они иначе будут пропущены
+			if isKey || idx == 0 {
+				buf = append(buf, '\\')
+			}
+			buf = append(buf, ' ')
+		case '=', ':', '#', '!':
+			if isKey {
+				buf = append(buf, '\\')
+			}
+			buf = append(buf, byte(r))
+		default:
+			if r < 0x20 || r > 0x7E {
+				buf = appendUnicodeEscape(buf, r)
+			} else {
+				buf = append(buf, byte(r))
+			}
+		}
+	}
+
+	return buf
+}
+
+// appendUnicodeEscape - символы вне BMP записываются суррогатной парой из двух \uXXXX
+func appendUnicodeEscape(buf []byte, r rune) []byte {
+	if r > 0xFFFF {
+		high, low := utf16.EncodeRune(r)
+		return appendUnicodeEscape(appendUnicodeEscape(buf, high), low)
+	}
+
+	buf = append(buf, '\\', 'u')
+	for shift := 12; shift >= 0; shift -= 4 {
+		buf = append(buf, hexDigits[(r>>shift)&0xF])
+	}
+
+	return buf
+}
+
+func unescapeProperty(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			builder.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			break // одиночный \ в конце данных отбрасывается, как в Java
+		}
+
+		switch s[i] {
+		case 't':
+			builder.WriteByte('\t')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 'f':
+			builder.WriteByte('\f')
+		case 'u':
+			r, ok := parseUnicodeEscape(s[i+1:])
+			if !ok {
+				return "", fmt.Errorf("%w: malformed \\uxxxx encoding", ErrMalformedLine)
+			}
+			i += 4
+
+			if utf16.IsSurrogate(r) && strings.HasPrefix(s[i+1:], `\u`) {
+				if low, ok := parseUnicodeEscape(s[i+3:]); ok {
+					if decoded := utf16.DecodeRune(r, low); decoded != unicode.ReplacementChar {
+						r = decoded
+						i += 6
+					}
+				}
+			}
+			builder.WriteRune(r)
+		default:
+			builder.WriteByte(s[i])
+		}
+	}
+
+	return builder.String(), nil
+}
+
+func parseUnicodeEscape(s string) (rune, bool) {
+	if len(s) < 4 {
+		return 0, false
+	}
+
+	var r rune
+	for i := 0; i < 4; i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'f' {
+			c -= 'a' - 'A'
+		}
+
+		idx := strings.IndexByte(hexDigits, c)
+		if idx < 0 {
+			return 0, false
+		}
+		r = r<<4 | rune(idx)
+	}
+
+	return r, true
+}
+
+func isPropertySpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\f'
+}
+
+func trimPropertySpace(s string) string {
+	i := 0
+	for i < len(s) && isPropertySpace(s[i]) {
+		i++
+	}
+
+	return s[i:]
+}
+
+// splitKeyValue - ключ заканчивается на первом неэкранированном =, : или пробельном символе,
+// после него пропускаются пробелы и не более одного разделителя = или :
+func splitKeyValue(line string) (key, value string) {
+	i := 0
+	for i < len(line) {
+		c := line[i]
+		if c == '\\' {
+			i += 2
+			continue
+		}
+		if c == '=' || c == ':' || isPropertySpace(c) {
+			break
+		}
+		i++
+	}
+
+	i = min(i, len(line))
+	key, value = line[:i], trimPropertySpace(line[i:])
+	if value != "" && (value[0] == '=' || value[0] == ':') {
+		value = trimPropertySpace(value[1:])
+	}
+
+	return key, value
+}
+
+// lineReader - читает логические строки: пропускает комментарии и пустые строки,
+// склеивает строки с продолжением
+type lineReader struct {
+	reader *bufio.Reader
+	number int // номер последней прочитанной физической строки
+	eof    bool
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{reader: bufio.NewReader(r)}
+}
+
+// readNatural - прочитать физическую строку, завершенную \n, \r или \r\n
+func (r *lineReader) readNatural() (string, error) {
+	if r.eof {
+		return "", io.EOF
+	}
+
+	var builder strings.Builder
+	for {
+		c, err := r.reader.ReadByte()
+		if err == io.EOF {
+			r.eof = true
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if c == '\n' {
+			break
+		}
+		if c == '\r' {
+			if next, err := r.reader.Peek(1); err == nil && next[0] == '\n' {
+				_, _ = r.reader.ReadByte()
+			}
+			break
+		}
+		builder.WriteByte(c)
+	}
+
+	r.number++
+	return builder.String(), nil
+}
+
+// next - прочитать логическую строку и номер ее первой физической строки
+func (r *lineReader) next() (string, int, error) {
+	for {
+		line, err := r.readNatural()
+		if err != nil {
+			return "", 0, err
+		}
+
+		line = trimPropertySpace(line)
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		number := r.number
+		for continues(line) {
+			line = line[:len(line)-1]
+			part, err := r.readNatural()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", 0, err
+			}
+			line += trimPropertySpace(part)
+		}
+
+		return line, number, nil
+	}
+}
+
+// continues - строка продолжается, если заканчивается нечетным числом \
+func continues(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+
+	return count%2 == 1
+}
+
+func TestEscaping(t *testing.T) {
+	tests := map[string]struct {
+		key    string
+		value  string
+		result string
+	}{
+		"test case with plain text": {
+			key: "name", value: "John Doe",
+			result: "name=John Doe",
+		},
+		"test case with separators": {
+			key: "a=b:c d", value: "x=y:z",
+			result: `a\=b\:c\ d=x=y:z`,
+		},
+		"test case with comment characters": {
+			key: "#key!", value: "#value!",
+			result: `\#key\!=#value!`,
+		},
+		"test case with leading spaces": {
+			key: "key", value: "  two spaces",
+			result: `key=\  two spaces`,
+		},
+		"test case with control characters": {
+			key: "key", value: "line1\nline2\ttab\\slash\r\f",
+			result: `key=line1\nline2\ttab\\slash\r\f`,
+		},
+		"test case with non-ASCII": {
+			key: "город", value: "Zürich 😀",
+			result: `\u0433\u043E\u0440\u043E\u0434=Z\u00FCrich \uD83D\uDE00`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			line := escapeKey(test.key) + "=" + escapeValue(test.value)
+			assert.Equal(t, test.result, line)
+
+			key, value := splitKeyValue(line)
+			key, err := unescapeProperty(key)
+			assert.NoError(t, err)
+			value, err = unescapeProperty(value)
+			assert.NoError(t, err)
+			assert.Equal(t, test.key, key)
+			assert.Equal(t, test.value, value)
+		})
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	data := "# comment\r\n" +
+		"  ! another comment \\\n" +
+		"first = value\n" +
+		"second:value\r" +
+		"third   value with spaces\n" +
+		"\n" +
+		"fourth = long \\\n" +
+		"         continued \\\\\n" +
+		"fifth\\\n" +
+		"   key=v\n" +
+		"   \n" +
+		"sixth"
+
+	type line struct {
+		key, value string
+		number     int
+	}
+	expected := []line{
+		{key: "first", value: "value", number: 3},
+		{key: "second", value: "value", number: 4},
+		{key: "third", value: "value with spaces", number: 5},
+		{key: "fourth", value: `long continued \\`, number: 7},
+		{key: "fifthkey", value: "v", number: 9},
+		{key: "sixth", value: "", number: 12},
+	}
+
+	reader := newLineReader(strings.NewReader(data))
+	var actual []line
+	for {
+		text, number, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+
+		key, value := splitKeyValue(text)
+		actual = append(actual, line{key: key, value: value, number: number})
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestUnescapeErrors(t *testing.T) {
+	_, err := unescapeProperty(`\u12`)
+	assert.ErrorIs(t, err, ErrMalformedLine)
+	_, err = unescapeProperty(`\uZZZZ`)
+	assert.ErrorIs(t, err, ErrMalformedLine)
+
+	value, err := unescapeProperty(`\uD83D`)
+	assert.NoError(t, err)
+	assert.Equal(t, string(unicode.ReplacementChar), value)
+
+	value, err = unescapeProperty(`\uD83D\uDE00\q`)
+	assert.NoError(t, err)
+	assert.Equal(t, "😀q", value)
+}
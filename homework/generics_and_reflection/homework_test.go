@@ -1,15 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
-// go test -v homework_test.go
+// go test -v homework_test.go deserialize_test.go format_test.go plan_test.go marshaler_test.go stream_test.go
 
 type Person struct {
 	Name    string `properties:"name"`
@@ -18,30 +20,48 @@ type Person struct {
 	Married bool   `properties:"married"`
 }
 
-func Serialize(person Person) string {
-	v := reflect.ValueOf(person)
-	vType := v.Type()
-	res := make([]string, 0, vType.NumField())
+// Serialize - сериализовать любую структуру (или указатель на нее) в формат properties;
+// при любой ошибке (не структура, ошибка маршалера, пустое required-поле) возвращает
+// пустую строку, которую нельзя отличить от пустого документа, поэтому там, где ошибки
+// важны, нужно использовать Marshal или Encoder
+func Serialize(v any) string {
+	result, err := Marshal(v)
+	if err != nil {
+		return ""
+	}
 
-	for i := 0; i < v.NumField(); i++ {
-		field := vType.Field(i)
+	return result
+}
 
-		key, options := parseTag(field)
-		if key == "" {
-			continue
-		}
+// Marshal - то же, что и Serialize, но возвращает ошибку вместо пустой строки
+func Marshal(v any) (string, error) {
+	value, ok := structOf(v)
+	if !ok {
+		return "", fmt.Errorf("%w: got %T", ErrNotStruct, v)
+	}
 
-		fieldValue := v.Field(i)
-		if shouldSkipField(options, fieldValue) {
-			continue
-		}
+	state := encodeStatePool.Get().(*encodeState)
+	defer encodeStatePool.Put(state)
 
-		valueStr := formatValue(fieldValue)
-		res = append(res, key+"="+valueStr)
+	state.reset()
+	encoderFor(value.Type())(state, value)
+	if state.err != nil {
+		return "", state.err
+	}
+
+	return string(bytes.TrimSuffix(state.buf, []byte{'\n'})), nil
+}
 
+func structOf(v any) (reflect.Value, bool) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return reflect.Value{}, false
+		}
+		value = value.Elem()
 	}
 
-	return strings.Join(res, "\n")
+	return value, value.Kind() == reflect.Struct
 }
 
 func parseTag(field reflect.StructField) (key string, options []string) {
@@ -51,22 +71,14 @@ func parseTag(field reflect.StructField) (key string, options []string) {
 	return tagParts[0], tagParts[1:]
 }
 
-func formatValue(val reflect.Value) string {
-	switch val.Kind() {
-	case reflect.String:
-		return val.String()
-	case reflect.Bool:
-		return fmt.Sprintf("%t", val.Bool())
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return fmt.Sprintf("%d", val.Int())
-	default:
-		return fmt.Sprintf("%v", val.Interface())
-	}
-}
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
 
-func shouldSkipField(opts []string, val reflect.Value) bool {
+func hasOption(opts []string, name string) bool {
 	for _, opt := range opts {
-		if opt == "omitempty" && val.IsZero() {
+		if opt == name {
 			return true
 		}
 	}
@@ -107,3 +119,70 @@ func TestSerialization(t *testing.T) {
 		})
 	}
 }
+
+type Address struct {
+	City   string `properties:"city"`
+	Street string `properties:"street,omitempty"`
+}
+
+type Employee struct {
+	ID       uint64        `properties:"id"`
+	Rating   float64       `properties:"rating"`
+	Timeout  time.Duration `properties:"timeout"`
+	Hired    time.Time     `properties:"hired"`
+	Manager  *string       `properties:"manager,omitempty"`
+	Address  Address       `properties:"address"`
+	Previous *Address      `properties:"previous"`
+	Tags     []string      `properties:"tags"`
+	Scores   [2]uint8      `properties:"scores"`
+	internal int
+}
+
+func TestSerializationOfAnyStruct(t *testing.T) {
+	manager := "Jane"
+	employee := Employee{
+		ID:      42,
+		Rating:  4.5,
+		Timeout: 1500 * time.Millisecond,
+		Hired:   time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC),
+		Manager: &manager,
+		Address: Address{City: "Paris"},
+		Tags:    []string{"go", "backend"},
+		Scores:  [2]uint8{7, 255},
+	}
+
+	tests := map[string]struct {
+		value  any
+		result string
+	}{
+		"test case with struct": {
+			value: employee,
+			result: "id=42\nrating=4.5\ntimeout=1.5s\nhired=2024-03-01T09:30:00Z\nmanager=Jane\n" +
+				"address.city=Paris\ntags.0=go\ntags.1=backend\nscores.0=7\nscores.1=255",
+		},
+		"test case with pointer to struct": {
+			value:  &Address{City: "Berlin", Street: "Main"},
+			result: "city=Berlin\nstreet=Main",
+		},
+		"test case with nested pointer": {
+			value: Employee{Previous: &Address{City: "Rome"}},
+			result: "id=0\nrating=0\ntimeout=0s\nhired=0001-01-01T00:00:00Z\n" +
+				"address.city=\nprevious.city=Rome\nscores.0=0\nscores.1=0",
+		},
+		"test case with nil pointer": {
+			value:  (*Address)(nil),
+			result: "",
+		},
+		"test case with not a struct": {
+			value:  42,
+			result: "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := Serialize(test.value)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go deserialize_test.go format_test.go plan_test.go marshaler_test.go stream_test.go
+
+// PropertiesMarshaler - тип сам формирует значение для своего ключа
+type PropertiesMarshaler interface {
+	MarshalProperties() (string, error)
+}
+
+// PropertiesUnmarshaler - тип сам разбирает значение своего ключа
+type PropertiesUnmarshaler interface {
+	UnmarshalProperties(value string) error
+}
+
+var (
+	ErrNotStruct     = errors.New("value must be a struct or a pointer to struct")
+	ErrRequiredField = errors.New("required field is empty")
+)
+
+var (
+	propertiesMarshalerType   = reflect.TypeFor[PropertiesMarshaler]()
+	propertiesUnmarshalerType = reflect.TypeFor[PropertiesUnmarshaler]()
+	textMarshalerType         = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType       = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+func implementsMarshaler(t reflect.Type) bool {
+	return t.Implements(propertiesMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// newMarshalerEncoder - кодировщик через PropertiesMarshaler или encoding.TextMarshaler (nil, если их нет)
+func newMarshalerEncoder(t reflect.Type) encoderFunc {
+	if t == timeType {
+		return nil // у time.Time свой путь без лишних аллокаций
+	}
+
+	if implementsMarshaler(t) {
+		return marshalerEncoder
+	}
+
+	// методы с получателем-указателем вызываются и для значений, переданных по
+	// значению: иначе, например, маскирующий MarshalText молча пропускался бы
+	if pointer := reflect.PointerTo(t); t.Kind() != reflect.Pointer && implementsMarshaler(pointer) {
+		return func(state *encodeState, v reflect.Value) {
+			if !v.CanAddr() {
+				addressable := reflect.New(t).Elem()
+				addressable.Set(v)
+				v = addressable
+			}
+			marshalerEncoder(state, v.Addr())
+		}
+	}
+
+	return nil
+}
+
+func marshalerEncoder(state *encodeState, v reflect.Value) {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return
+	}
+
+	var text string
+	var err error
+	switch marshaler := v.Interface().(type) {
+	case PropertiesMarshaler:
+		text, err = marshaler.MarshalProperties()
+	case encoding.TextMarshaler:
+		var raw []byte
+		raw, err = marshaler.MarshalText()
+		text = string(raw)
+	}
+
+	if err != nil {
+		state.setError(fmt.Errorf("key %q: %w", state.key, err))
+		return
+	}
+
+	state.buf = append(state.buf, state.key...)
+	state.buf = append(state.buf, '=')
+	state.buf = appendEscaped(state.buf, text, false)
+	state.buf = append(state.buf, '\n')
+	state.lineDone()
+}
+
+func isUnmarshaler(v reflect.Value) bool {
+	if !v.CanAddr() {
+		return false
+	}
+
+	pointer := reflect.PointerTo(v.Type())
+	return pointer.Implements(propertiesUnmarshalerType) || pointer.Implements(textUnmarshalerType)
+}
+
+// isQuotable - опция string применяется только к числам и bool
+func isQuotable(t reflect.Type) bool {
+	if implementsMarshaler(t) || implementsMarshaler(reflect.PointerTo(t)) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func newQuotedEncoder(t reflect.Type) encoderFunc {
+	if !isQuotable(t) {
+		return nil
+	}
+
+	appendValue := newValueAppender(t)
+	return scalarEncoder(func(buf []byte, v reflect.Value) []byte {
+		buf = append(buf, '"')
+		buf = appendValue(buf, v)
+		return append(buf, '"')
+	})
+}
+
+func isStructOrPointerToStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct
+}
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "error"}
+
+func (l Level) MarshalProperties() (string, error) {
+	if l < 0 || int(l) >= len(levelNames) {
+		return "", fmt.Errorf("unknown level %d", int(l))
+	}
+
+	return levelNames[l], nil
+}
+
+func (l *Level) UnmarshalProperties(value string) error {
+	for idx, name := range levelNames {
+		if name == value {
+			*l = Level(idx)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown level %q", value)
+}
+
+type UUID [16]byte
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(u[:])), nil
+}
+
+func (u *UUID) UnmarshalText(text []byte) error {
+	if hex.DecodedLen(len(text)) != len(u) {
+		return fmt.Errorf("bad uuid length %d", len(text))
+	}
+
+	_, err := hex.Decode(u[:], text)
+	return err
+}
+
+// Secret - маскируется и при передаче структуры по значению, хотя метод у указателя
+type Secret string
+
+func (s *Secret) MarshalText() ([]byte, error) {
+	return []byte("***"), nil
+}
+
+type Metadata struct {
+	Owner string `properties:"owner"`
+}
+
+type Service struct {
+	Metadata `properties:",inline"`
+	ID       UUID   `properties:"id"`
+	Name     string `properties:"name,required"`
+	Level    Level  `properties:"level"`
+	Port     int    `properties:"port,string"`
+	Enabled  bool   `properties:"enabled,string"`
+	Token    string `properties:"-"`
+	Password Secret `properties:"password"`
+	Upstream *UUID  `properties:"upstream,omitempty"`
+}
+
+func TestMarshalerAndOptions(t *testing.T) {
+	service := Service{
+		Metadata: Metadata{Owner: "team"},
+		ID:       UUID{0x01, 0x02, 0xAB},
+		Name:     "billing",
+		Level:    LevelError,
+		Port:     8080,
+		Enabled:  true,
+		Token:    "secret",
+		Password: "qwerty",
+	}
+
+	expected := "owner=team\nid=0102ab00000000000000000000000000\nname=billing\nlevel=error\n" +
+		"port=\"8080\"\nenabled=\"true\"\npassword=***"
+	assert.Equal(t, expected, Serialize(service))
+	assert.Equal(t, expected, Serialize(&service))
+
+	var decoded Service
+	assert.NoError(t, Deserialize(Serialize(service), &decoded, WithStrictKeys()))
+	service.Token = ""
+	service.Password = "***" // пароль не попадает в документ
+	assert.Equal(t, service, decoded)
+}
+
+func TestMarshalerErrors(t *testing.T) {
+	_, err := Marshal(Service{Level: LevelError})
+	assert.ErrorIs(t, err, ErrRequiredField)
+	assert.Equal(t, "", Serialize(Service{}))
+
+	_, err = Marshal(Service{Name: "billing", Level: Level(10)})
+	assert.ErrorContains(t, err, "unknown level 10")
+
+	_, err = Marshal(42)
+	assert.ErrorIs(t, err, ErrNotStruct)
+
+	var decoded Service
+	assert.ErrorIs(t, Deserialize("level=debug", &decoded), ErrRequiredField)
+	assert.ErrorIs(t, Deserialize("name=a\nlevel=trace", &decoded), ErrInvalidValue)
+	assert.ErrorIs(t, Deserialize("name=a\nport=8080", &decoded), ErrInvalidValue)
+	assert.ErrorIs(t, Deserialize("name=a\nid=xyz", &decoded), ErrInvalidValue)
+}
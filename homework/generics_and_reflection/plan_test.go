@@ -0,0 +1,436 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -bench=. -benchmem homework_test.go deserialize_test.go format_test.go plan_test.go marshaler_test.go stream_test.go
+
+// encodeState - переиспользуемые буферы для результата и текущего ключа
+type encodeState struct {
+	buf []byte
+	key []byte
+	err error     // первая ошибка кодирования
+	w   io.Writer // если задан, заполненный буфер сбрасывается в него по мере кодирования
+}
+
+func (e *encodeState) reset() {
+	e.buf = e.buf[:0]
+	e.key = e.key[:0]
+	e.err = nil
+	e.w = nil
+}
+
+// lineDone - вызывается после каждой записанной строки
+func (e *encodeState) lineDone() {
+	if e.w != nil && len(e.buf) >= flushThreshold {
+		e.flush()
+	}
+}
+
+func (e *encodeState) flush() {
+	if e.err == nil && len(e.buf) > 0 {
+		if _, err := e.w.Write(e.buf); err != nil {
+			e.setError(err)
+		}
+	}
+	e.buf = e.buf[:0]
+}
+
+func (e *encodeState) setError(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+var encodeStatePool = sync.Pool{
+	New: func() any {
+		return &encodeState{
+			buf: make([]byte, 0, 256),
+			key: make([]byte, 0, 64),
+		}
+	},
+}
+
+// encoderFunc - дописывает в state.buf строки key=value для значения под ключом state.key
+type encoderFunc func(state *encodeState, v reflect.Value)
+
+// fieldPlan - все, что нужно знать о поле, вычисляется один раз для типа
+type fieldPlan struct {
+	index     int
+	key       []byte // уже экранированная часть ключа
+	omitEmpty bool
+	required  bool
+	inline    bool // поля вложенной структуры пишутся без ее ключа
+	encoder   encoderFunc
+}
+
+var encoderCache sync.Map // map[reflect.Type]encoderFunc
+
+// encoderFor - получить (или построить и закэшировать) кодировщик для типа
+func encoderFor(t reflect.Type) encoderFunc {
+	if encoder, ok := encoderCache.Load(t); ok {
+		return encoder.(encoderFunc)
+	}
+
+	// для рекурсивных типов (type Node struct { Children []Node }) сначала
+	// кладем в кэш косвенный кодировщик, который дождется построения настоящего
+	var wg sync.WaitGroup
+	var encoder encoderFunc
+	wg.Add(1)
+	indirect, loaded := encoderCache.LoadOrStore(t, encoderFunc(func(state *encodeState, v reflect.Value) {
+		wg.Wait()
+		encoder(state, v)
+	}))
+	if loaded {
+		return indirect.(encoderFunc)
+	}
+
+	encoder = newEncoder(t)
+	wg.Done()
+	encoderCache.Store(t, encoder)
+
+	return encoder
+}
+
+func newEncoder(t reflect.Type) encoderFunc {
+	if encoder := newMarshalerEncoder(t); encoder != nil {
+		return encoder
+	}
+
+	return newKindEncoder(t)
+}
+
+func newKindEncoder(t reflect.Type) encoderFunc {
+	if appendValue := newValueAppender(t); appendValue != nil {
+		return scalarEncoder(appendValue)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return newPointerEncoder(t)
+	case reflect.Interface:
+		return func(state *encodeState, v reflect.Value) {
+			if !v.IsNil() {
+				encoderFor(v.Elem().Type())(state, v.Elem())
+			}
+		}
+	case reflect.Struct:
+		return newStructEncoder(t)
+	case reflect.Slice, reflect.Array:
+		return newSliceEncoder(t)
+	default:
+		return scalarEncoder(func(buf []byte, v reflect.Value) []byte {
+			return appendEscaped(buf, fmt.Sprintf("%v", v.Interface()), false)
+		})
+	}
+}
+
+// valueAppender - дописывает в буфер значение скалярного типа
+type valueAppender func(buf []byte, v reflect.Value) []byte
+
+// newValueAppender - nil для составных типов
+func newValueAppender(t reflect.Type) valueAppender {
+	switch t {
+	case timeType:
+		return func(buf []byte, v reflect.Value) []byte {
+			return v.Interface().(time.Time).AppendFormat(buf, time.RFC3339Nano)
+		}
+	case durationType:
+		return func(buf []byte, v reflect.Value) []byte {
+			return appendEscaped(buf, time.Duration(v.Int()).String(), false)
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return func(buf []byte, v reflect.Value) []byte {
+			return appendEscaped(buf, v.String(), false)
+		}
+	case reflect.Bool:
+		return func(buf []byte, v reflect.Value) []byte {
+			return strconv.AppendBool(buf, v.Bool())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(buf []byte, v reflect.Value) []byte {
+			return strconv.AppendInt(buf, v.Int(), 10)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(buf []byte, v reflect.Value) []byte {
+			return strconv.AppendUint(buf, v.Uint(), 10)
+		}
+	case reflect.Float32, reflect.Float64:
+		bits := t.Bits()
+		return func(buf []byte, v reflect.Value) []byte {
+			return strconv.AppendFloat(buf, v.Float(), 'g', -1, bits)
+		}
+	default:
+		return nil
+	}
+}
+
+func scalarEncoder(appendValue valueAppender) encoderFunc {
+	return func(state *encodeState, v reflect.Value) {
+		state.buf = append(state.buf, state.key...)
+		state.buf = append(state.buf, '=')
+		state.buf = appendValue(state.buf, v)
+		state.buf = append(state.buf, '\n')
+		state.lineDone()
+	}
+}
+
+func newPointerEncoder(t reflect.Type) encoderFunc {
+	elemEncoder := encoderFor(t.Elem())
+	return func(state *encodeState, v reflect.Value) {
+		if !v.IsNil() {
+			elemEncoder(state, v.Elem())
+		}
+	}
+}
+
+func newStructEncoder(t reflect.Type) encoderFunc {
+	fields := make([]fieldPlan, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		key, options := parseTag(field)
+		inline := hasOption(options, "inline") && isStructOrPointerToStruct(field.Type)
+		if key == "-" || (key == "" && !inline) {
+			continue
+		}
+
+		plan := fieldPlan{
+			index:     i,
+			key:       appendEscaped(nil, key, true),
+			omitEmpty: hasOption(options, "omitempty"),
+			required:  hasOption(options, "required"),
+			inline:    inline,
+			encoder:   encoderFor(field.Type),
+		}
+		if hasOption(options, "string") {
+			if encoder := newQuotedEncoder(field.Type); encoder != nil {
+				plan.encoder = encoder
+			}
+		}
+
+		fields = append(fields, plan)
+	}
+
+	return func(state *encodeState, v reflect.Value) {
+		base := len(state.key)
+		for idx := range fields {
+			field := &fields[idx]
+
+			fieldValue := v.Field(field.index)
+			if (field.required || field.omitEmpty) && fieldValue.IsZero() {
+				if field.required {
+					state.setError(fmt.Errorf("%w: %q", ErrRequiredField, joinKey(state.key, field.key)))
+					return
+				}
+				if field.omitEmpty {
+					continue
+				}
+			}
+
+			if !field.inline {
+				if base > 0 {
+					state.key = append(state.key, '.')
+				}
+				state.key = append(state.key, field.key...)
+			}
+			field.encoder(state, fieldValue)
+			state.key = state.key[:base]
+		}
+	}
+}
+
+func joinKey(prefix, key []byte) string {
+	if len(prefix) == 0 {
+		return string(key)
+	}
+
+	return string(prefix) + "." + string(key)
+}
+
+func newSliceEncoder(t reflect.Type) encoderFunc {
+	elemEncoder := encoderFor(t.Elem())
+	return func(state *encodeState, v reflect.Value) {
+		base := len(state.key)
+		for i := 0; i < v.Len(); i++ {
+			state.key = append(state.key, '.')
+			state.key = strconv.AppendInt(state.key, int64(i), 10)
+			elemEncoder(state, v.Index(i))
+			state.key = state.key[:base]
+		}
+	}
+}
+
+type TreeNode struct {
+	Name     string     `properties:"name"`
+	Children []TreeNode `properties:"children"`
+	Parent   *TreeNode  `properties:"parent,omitempty"`
+}
+
+func TestSerializationPlan(t *testing.T) {
+	manager := "Jane"
+	tests := map[string]struct {
+		value  any
+		result string
+	}{
+		"test case with omitempty field": {
+			value:  Person{Name: "John Doe", Address: "Paris", Age: 30, Married: true},
+			result: "name=John Doe\naddress=Paris\nage=30\nmarried=true",
+		},
+		"test case with escaped value": {
+			value:  &Person{Name: " leading space", Age: -1},
+			result: "name=\\ leading space\nage=-1\nmarried=false",
+		},
+		"test case with nested and escaped values": {
+			value: Employee{
+				ID:       42,
+				Rating:   4.5,
+				Timeout:  1500 * time.Microsecond,
+				Hired:    time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC),
+				Manager:  &manager,
+				Address:  Address{City: "Zürich", Street: "a=b"},
+				Previous: &Address{City: "Rome"},
+				Tags:     []string{"go", "line\nbreak"},
+			},
+			result: "id=42\nrating=4.5\ntimeout=1.5ms\nhired=2024-03-01T09:30:00Z\nmanager=Jane\n" +
+				"address.city=Z\\u00FCrich\naddress.street=a=b\nprevious.city=Rome\n" +
+				"tags.0=go\ntags.1=line\\nbreak\nscores.0=0\nscores.1=0",
+		},
+		"test case with recursive type": {
+			value:  TreeNode{Name: "root", Children: []TreeNode{{Name: "a"}, {Name: "b", Children: []TreeNode{{Name: "c"}}}}},
+			result: "name=root\nchildren.0.name=a\nchildren.1.name=b\nchildren.1.children.0.name=c",
+		},
+		"test case with not a struct": {
+			value:  42,
+			result: "",
+		},
+		"test case with nil pointer": {
+			value:  (*Person)(nil),
+			result: "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.result, Serialize(test.value))
+		})
+	}
+}
+
+func TestSerializationPlanConcurrent(t *testing.T) {
+	type Fresh struct {
+		Value int      `properties:"value"`
+		Node  TreeNode `properties:"node"`
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.Equal(t, "value="+strconv.Itoa(i)+"\nnode.name=", Serialize(Fresh{Value: i}))
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+var benchmarkEmployee = Employee{
+	ID:      42,
+	Rating:  4.5,
+	Timeout: 1500 * time.Millisecond,
+	Hired:   time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC),
+	Address: Address{City: "Paris", Street: "Rue de Rivoli"},
+	Tags:    []string{"go", "backend", "properties"},
+	Scores:  [2]uint8{7, 255},
+}
+
+// serializeWithoutPlan - эталон для бенчмарка: обход полей без кэширования,
+// теги разбираются и значения форматируются через fmt на каждом вызове
+func serializeWithoutPlan(v any) string {
+	value, ok := structOf(v)
+	if !ok {
+		return ""
+	}
+
+	return strings.Join(serializeStructWithoutPlan(value, "", nil), "\n")
+}
+
+func serializeStructWithoutPlan(v reflect.Value, prefix string, res []string) []string {
+	vType := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		key, options := parseTag(vType.Field(i))
+		if key == "" || key == "-" {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		if hasOption(options, "omitempty") && fieldValue.IsZero() {
+			continue
+		}
+
+		res = serializeValueWithoutPlan(fieldValue, prefix+key, res)
+	}
+
+	return res
+}
+
+func serializeValueWithoutPlan(v reflect.Value, key string, res []string) []string {
+	if v.Type() == timeType {
+		formatted := v.Interface().(time.Time).Format(time.RFC3339Nano)
+		return append(res, escapeKey(key)+"="+escapeValue(formatted))
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return res
+		}
+		return serializeValueWithoutPlan(v.Elem(), key, res)
+	case reflect.Struct:
+		return serializeStructWithoutPlan(v, key+".", res)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			res = serializeValueWithoutPlan(v.Index(i), key+"."+strconv.Itoa(i), res)
+		}
+		return res
+	case reflect.Float32, reflect.Float64:
+		return append(res, escapeKey(key)+"="+strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))
+	default:
+		return append(res, escapeKey(key)+"="+escapeValue(fmt.Sprint(v.Interface())))
+	}
+}
+
+func BenchmarkSerialize(b *testing.B) {
+	// эталон должен давать тот же результат, иначе сравнение нечестное
+	if serializeWithoutPlan(benchmarkEmployee) != Serialize(benchmarkEmployee) {
+		b.Fatal("reference serializer differs from Serialize")
+	}
+
+	b.Run("without plan", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = serializeWithoutPlan(benchmarkEmployee)
+		}
+	})
+
+	b.Run("with plan", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = Serialize(benchmarkEmployee)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go deserialize_test.go format_test.go plan_test.go marshaler_test.go stream_test.go
+
+// flushThreshold - при потоковом кодировании буфер сбрасывается в io.Writer, как только достигает этого размера
+const flushThreshold = 4096
+
+// Encoder - пишет структуры в формате properties в io.Writer
+type Encoder struct {
+	w io.Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode - записать v построчно, каждая строка завершается \n; при ошибке
+// в w уже может оказаться часть строк
+func (e *Encoder) Encode(v any) error {
+	value, ok := structOf(v)
+	if !ok {
+		return fmt.Errorf("%w: got %T", ErrNotStruct, v)
+	}
+
+	state := encodeStatePool.Get().(*encodeState)
+	defer encodeStatePool.Put(state)
+
+	state.reset()
+	defer state.reset() // не держим ссылку на w в пуле
+
+	state.w = e.w
+	encoderFor(value.Type())(state, value)
+	if state.err == nil {
+		state.flush()
+	}
+
+	return state.err
+}
+
+// Decoder - читает структуры в формате properties из io.Reader
+type Decoder struct {
+	reader  *lineReader
+	options []DeserializeOption
+}
+
+func NewDecoder(r io.Reader, options ...DeserializeOption) *Decoder {
+	return &Decoder{reader: newLineReader(r), options: options}
+}
+
+// Decode - прочитать поток до конца и заполнить структуру, на которую указывает out;
+// в памяти хранятся только разобранные пары ключ-значение, а не исходный текст
+func (d *Decoder) Decode(out any) error {
+	value := reflect.ValueOf(out)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: got %T", ErrInvalidTarget, out)
+	}
+
+	des := deserializer{entries: make(map[string]*entry)}
+	for _, option := range d.options {
+		option(&des)
+	}
+
+	for {
+		line, number, err := d.reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &LineError{Line: d.reader.number + 1, Err: err}
+		}
+
+		if err := des.parseLine(line, number); err != nil {
+			return err
+		}
+	}
+
+	des.buildIndex()
+	if err := des.decodeStruct(value.Elem(), ""); err != nil {
+		return err
+	}
+
+	return des.checkUnknown()
+}
+
+type Inventory struct {
+	Name  string          `properties:"name"`
+	Items []InventoryItem `properties:"items"`
+}
+
+type InventoryItem struct {
+	SKU   string  `properties:"sku"`
+	Count int     `properties:"count"`
+	Price float64 `properties:"price"`
+}
+
+// failingWriter - принимает limit байт, затем возвращает ошибку
+type failingWriter struct {
+	limit int
+	err   error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, w.err
+	}
+
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+// failingReader - отдает data, затем возвращает ошибку вместо io.EOF
+type failingReader struct {
+	data io.Reader
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if err == io.EOF {
+		return n, r.err
+	}
+
+	return n, err
+}
+
+func TestEncoder(t *testing.T) {
+	values := []any{
+		Person{Name: "John Doe", Address: "Paris", Age: 30, Married: true},
+		&Person{Name: " leading space", Age: -1},
+		benchmarkEmployee,
+		TreeNode{Name: "root", Children: []TreeNode{{Name: "a"}, {Name: "b"}}},
+	}
+
+	for _, value := range values {
+		var buffer bytes.Buffer
+		assert.NoError(t, NewEncoder(&buffer).Encode(value))
+		assert.Equal(t, Serialize(value)+"\n", buffer.String())
+	}
+
+	var buffer bytes.Buffer
+	assert.ErrorIs(t, NewEncoder(&buffer).Encode(42), ErrNotStruct)
+	assert.ErrorIs(t, NewEncoder(&buffer).Encode(Service{}), ErrRequiredField)
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	inventory := Inventory{Name: "warehouse"}
+	for i := 0; i < 5000; i++ {
+		inventory.Items = append(inventory.Items, InventoryItem{
+			SKU:   "sku-" + strconv.Itoa(i),
+			Count: i,
+			Price: float64(i) / 4,
+		})
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(NewEncoder(writer).Encode(inventory))
+	}()
+
+	var decoded Inventory
+	assert.NoError(t, NewDecoder(reader, WithStrictKeys()).Decode(&decoded))
+	assert.Equal(t, inventory, decoded)
+}
+
+func TestStreamErrors(t *testing.T) {
+	errWrite := errors.New("disk is full")
+	inventory := Inventory{Name: "warehouse", Items: make([]InventoryItem, 1000)}
+	assert.ErrorIs(t, NewEncoder(&failingWriter{limit: 100, err: errWrite}).Encode(inventory), errWrite)
+
+	errRead := errors.New("connection reset")
+	var decoded Inventory
+	err := NewDecoder(&failingReader{data: strings.NewReader("name=a\nitems.0.sku=b\n"), err: errRead}).Decode(&decoded)
+	assert.ErrorIs(t, err, errRead)
+
+	var lineErr *LineError
+	assert.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 3, lineErr.Line)
+
+	err = NewDecoder(strings.NewReader("name=a\n# comment\nitems.0.count=many\n")).Decode(&decoded)
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 3, lineErr.Line)
+
+	assert.ErrorIs(t, NewDecoder(strings.NewReader("")).Decode(decoded), ErrInvalidTarget)
+}
+
+// BenchmarkDecode - время разбора должно расти линейно с количеством записей
+func BenchmarkDecode(b *testing.B) {
+	for _, size := range []int{1000, 4000} {
+		inventory := Inventory{Name: "warehouse", Items: make([]InventoryItem, size)}
+		data := Serialize(inventory)
+
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var decoded Inventory
+				if err := Deserialize(data, &decoded); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go delayed_test.go fairness_test.go executor_test.go
+
+// Clock - источник времени, в тестах подменяется на ручные часы
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// Schedule - расписание периодической задачи
+type Schedule interface {
+	// Next - время следующего запуска строго после after (нулевое время - запусков больше нет)
+	Next(after time.Time) time.Time
+}
+
+type interval time.Duration
+
+// Every - запуск с фиксированным интервалом
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("non-positive interval for Every")
+	}
+
+	return interval(d)
+}
+
+func (i interval) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(i))
+}
+
+var (
+	ErrInvalidCron        = errors.New("invalid cron expression")
+	ErrScheduleNeverFires = errors.New("schedule never fires")
+)
+
+// cronField - множество допустимых значений поля в виде битовой маски
+type cronField uint64
+
+func (f cronField) has(value int) bool {
+	return f&(1<<uint(value)) != 0
+}
+
+// cronSchedule - расписание в формате cron: минуты, часы, день месяца, месяц, день недели
+type cronSchedule struct {
+	minute, hour, day, month, weekday cronField
+	anyDay, anyWeekday                bool
+}
+
+// ParseCron - разобрать выражение из пяти полей, каждое - список из *, чисел
+// и диапазонов a-b с необязательным шагом /n (день недели 0 и 7 - воскресенье)
+func ParseCron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q: expected 5 fields", ErrInvalidCron, expr)
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var parsed [5]cronField
+	for idx, field := range fields {
+		value, err := parseCronField(field, bounds[idx][0], bounds[idx][1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidCron, expr, err)
+		}
+		parsed[idx] = value
+	}
+
+	if parsed[4].has(7) {
+		parsed[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute:     parsed[0],
+		hour:       parsed[1],
+		day:        parsed[2],
+		month:      parsed[3],
+		weekday:    parsed[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, low, high int) (cronField, error) {
+	var result cronField
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step %q", stepPart)
+			}
+		}
+
+		start, end := low, high
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("bad value %q", first)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("bad value %q", last)
+				}
+			} else if hasStep {
+				end = high
+			}
+		}
+
+		if start < low || end > high || start > end {
+			return 0, fmt.Errorf("%q out of range [%d, %d]", part, low, high)
+		}
+
+		for value := start; value <= end; value += step {
+			result |= 1 << uint(value)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	day, weekday := c.day.has(t.Day()), c.weekday.has(int(t.Weekday()))
+	if c.anyDay || c.anyWeekday {
+		return day && weekday
+	}
+
+	return day || weekday // как в cron: если заданы оба поля, достаточно одного
+}
+
+func (c *cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0) // выражение вроде "0 0 30 2 *" не сработает никогда
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case !c.month.has(int(month)):
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+		case !c.hour.has(t.Hour()):
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// delayedTask - задача, которая попадет в очередь приоритетов не раньше notBefore
+type delayedTask[K comparable, V any] struct {
+	task      Task[K, V]
+	notBefore time.Time
+	schedule  Schedule // nil для однократной задачи
+	index     int
+}
+
+type delayedHeap[K comparable, V any] []*delayedTask[K, V]
+
+func (h delayedHeap[K, V]) Len() int           { return len(h) }
+func (h delayedHeap[K, V]) Less(i, j int) bool { return h[i].notBefore.Before(h[j].notBefore) } // min-heap
+func (h delayedHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *delayedHeap[K, V]) Push(x interface{}) {
+	task := x.(*delayedTask[K, V])
+	task.index = len(*h)
+	*h = append(*h, task)
+}
+
+func (h *delayedHeap[K, V]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = nil
+	task.index = -1
+	*h = old[0 : n-1]
+	return task
+}
+
+// AddDelayedTask - запланировать задачу, которая станет доступна не раньше notBefore
+func (s *Scheduler[K, V]) AddDelayedTask(task Task[K, V], notBefore time.Time) error {
+	return s.addDelayed(task, notBefore, nil)
+}
+
+// AddRecurringTask - запланировать задачу, которая попадает в очередь по расписанию;
+// если предыдущий запуск еще не забрали из очереди, очередной пропускается,
+// а расписание без единого будущего запуска - ошибка ErrScheduleNeverFires
+func (s *Scheduler[K, V]) AddRecurringTask(task Task[K, V], schedule Schedule) error {
+	return s.addDelayed(task, time.Time{}, schedule)
+}
+
+func (s *Scheduler[K, V]) addDelayed(task Task[K, V], notBefore time.Time, schedule Schedule) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.contains(task.Identifier) {
+		return fmt.Errorf("%w: %v", ErrDuplicateTask, task.Identifier)
+	}
+
+	if schedule != nil {
+		if notBefore = schedule.Next(s.clock.Now()); notBefore.IsZero() {
+			return fmt.Errorf("%w: %v", ErrScheduleNeverFires, task.Identifier)
+		}
+	}
+
+	delayed := &delayedTask[K, V]{
+		task:      task.public(),
+		notBefore: notBefore,
+		schedule:  schedule,
+	}
+	s.delayedMap[task.Identifier] = delayed
+	heap.Push(&s.delayed, delayed)
+
+	s.wakeUp() // у ожидающих потребителей мог измениться ближайший срок
+	return nil
+}
+
+// promoteDue - перенести наступившие отложенные задачи в очередь приоритетов
+func (s *Scheduler[K, V]) promoteDue(now time.Time) {
+	for len(s.delayed) != 0 && !s.delayed[0].notBefore.After(now) {
+		delayed := s.delayed[0]
+
+		if _, queued := s.taskMap[delayed.task.Identifier]; !queued {
+			s.pushTask(delayed.task)
+		}
+
+		if delayed.schedule == nil {
+			heap.Pop(&s.delayed)
+			delete(s.delayedMap, delayed.task.Identifier)
+			continue
+		}
+
+		// пропущенные запуски (например, после долгого простоя) не накапливаются
+		next := delayed.schedule.Next(delayed.notBefore)
+		if !next.IsZero() && !next.After(now) {
+			next = delayed.schedule.Next(now)
+		}
+		if next.IsZero() {
+			heap.Pop(&s.delayed)
+			delete(s.delayedMap, delayed.task.Identifier)
+			continue
+		}
+
+		delayed.notBefore = next
+		heap.Fix(&s.delayed, delayed.index)
+	}
+}
+
+// fakeClock - часы, которые двигаются только через Advance
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	timer := &fakeTimer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.c <- c.now
+	} else {
+		c.timers = append(c.timers, timer)
+	}
+
+	return timer
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.deadline.After(c.now) {
+			pending = append(pending, timer)
+		} else {
+			timer.c <- c.now
+		}
+	}
+	c.timers = pending
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	for idx, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:idx], t.clock.timers[idx+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+var clockStart = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func TestDelayedTasks(t *testing.T) {
+	clock := newFakeClock(clockStart)
+	scheduler := NewScheduler[int, struct{}](WithClock(clock))
+
+	assert.NoError(t, scheduler.AddDelayedTask(intTask{Identifier: 1, Priority: 10}, clockStart.Add(time.Minute)))
+	assert.NoError(t, scheduler.AddDelayedTask(intTask{Identifier: 2, Priority: 50}, clockStart.Add(2*time.Minute)))
+	assert.NoError(t, scheduler.AddTask(intTask{Identifier: 3, Priority: 1}))
+	assert.ErrorIs(t, scheduler.AddTask(intTask{Identifier: 1, Priority: 1}), ErrDuplicateTask)
+	assert.Equal(t, 3, scheduler.Len())
+
+	task, ok := scheduler.TryGetTask()
+	assert.True(t, ok)
+	assert.Equal(t, intTask{Identifier: 3, Priority: 1}, task)
+
+	_, ok = scheduler.TryGetTask()
+	assert.False(t, ok)
+
+	clock.Advance(5 * time.Minute)
+	scheduler.ChangeTaskPriority(1, 100)
+
+	task, _ = scheduler.TryGetTask()
+	assert.Equal(t, intTask{Identifier: 1, Priority: 100}, task)
+	task, _ = scheduler.TryGetTask()
+	assert.Equal(t, intTask{Identifier: 2, Priority: 50}, task)
+	assert.Equal(t, 0, scheduler.Len())
+
+	assert.NoError(t, scheduler.AddDelayedTask(intTask{Identifier: 4}, clockStart.Add(time.Hour)))
+	assert.True(t, scheduler.RemoveTask(4))
+	clock.Advance(time.Hour)
+	_, ok = scheduler.TryGetTask()
+	assert.False(t, ok)
+}
+
+func TestGetTaskWaitsForDelayed(t *testing.T) {
+	clock := newFakeClock(clockStart)
+	scheduler := NewScheduler[int, struct{}](WithClock(clock))
+	assert.NoError(t, scheduler.AddDelayedTask(intTask{Identifier: 1, Priority: 10}, clockStart.Add(time.Minute)))
+
+	result := make(chan intTask)
+	go func() {
+		task, err := scheduler.GetTask(context.Background())
+		assert.NoError(t, err)
+		result <- task
+	}()
+
+	select {
+	case <-result:
+		t.Fatal("task is returned before it is due")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, intTask{Identifier: 1, Priority: 10}, <-result)
+}
+
+func TestRecurringTasks(t *testing.T) {
+	clock := newFakeClock(clockStart)
+	scheduler := NewScheduler[int, struct{}](WithClock(clock))
+	assert.NoError(t, scheduler.AddRecurringTask(intTask{Identifier: 1, Priority: 10}, Every(10*time.Second)))
+
+	for i := 0; i < 3; i++ {
+		_, ok := scheduler.TryGetTask()
+		assert.False(t, ok)
+
+		clock.Advance(10 * time.Second)
+		task, ok := scheduler.TryGetTask()
+		assert.True(t, ok)
+		assert.Equal(t, 1, task.Identifier)
+	}
+
+	// пропущенные запуски схлопываются в один
+	clock.Advance(time.Minute)
+	_, ok := scheduler.TryGetTask()
+	assert.True(t, ok)
+	_, ok = scheduler.TryGetTask()
+	assert.False(t, ok)
+
+	assert.True(t, scheduler.RemoveTask(1))
+	clock.Advance(time.Minute)
+	_, ok = scheduler.TryGetTask()
+	assert.False(t, ok)
+}
+
+// neverSchedule - расписание, у которого нет ни одного запуска
+type neverSchedule struct{}
+
+func (neverSchedule) Next(time.Time) time.Time {
+	return time.Time{}
+}
+
+func TestRecurringTaskCountedOnce(t *testing.T) {
+	clock := newFakeClock(clockStart)
+	scheduler := NewScheduler[int, struct{}](WithClock(clock))
+	assert.NoError(t, scheduler.AddRecurringTask(intTask{Identifier: 1}, Every(10*time.Second)))
+	assert.NoError(t, scheduler.AddTask(intTask{Identifier: 2}))
+	assert.Equal(t, 2, scheduler.Len())
+
+	// запуск попал в очередь, но задача остается и в расписании
+	clock.Advance(10 * time.Second)
+	_, ok := scheduler.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, 2, scheduler.Len())
+
+	err := scheduler.AddRecurringTask(intTask{Identifier: 3}, neverSchedule{})
+	assert.ErrorIs(t, err, ErrScheduleNeverFires)
+	assert.False(t, scheduler.Contains(3))
+	assert.Equal(t, 2, scheduler.Len())
+}
+
+func TestCronSchedule(t *testing.T) {
+	tests := map[string]struct {
+		expr  string
+		after time.Time
+		next  time.Time
+	}{
+		"test case with every minute": {
+			expr:  "* * * * *",
+			after: time.Date(2024, time.January, 1, 12, 0, 30, 0, time.UTC),
+			next:  time.Date(2024, time.January, 1, 12, 1, 0, 0, time.UTC),
+		},
+		"test case with step": {
+			expr:  "*/15 * * * *",
+			after: time.Date(2024, time.January, 1, 12, 46, 0, 0, time.UTC),
+			next:  time.Date(2024, time.January, 1, 13, 0, 0, 0, time.UTC),
+		},
+		"test case with weekdays": {
+			expr:  "30 9 * * 1-5",
+			after: time.Date(2024, time.January, 5, 10, 0, 0, 0, time.UTC), // пятница
+			next:  time.Date(2024, time.January, 8, 9, 30, 0, 0, time.UTC),
+		},
+		"test case with day or weekday": {
+			expr:  "0 0 13 * 5",
+			after: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			next:  time.Date(2024, time.January, 5, 0, 0, 0, 0, time.UTC),
+		},
+		"test case with leap day": {
+			expr:  "0 0 29 2 *",
+			after: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			next:  time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		"test case with never": {
+			expr:  "0 0 30 2 *",
+			after: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			next:  time.Time{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			schedule, err := ParseCron(test.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, test.next, schedule.Next(test.after))
+		})
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := ParseCron(expr)
+		assert.ErrorIs(t, err, ErrInvalidCron, expr)
+	}
+}
+
+func TestCronRecurringTask(t *testing.T) {
+	clock := newFakeClock(clockStart)
+	scheduler := NewScheduler[int, struct{}](WithClock(clock))
+
+	schedule, err := ParseCron("0 * * * *")
+	assert.NoError(t, err)
+	assert.NoError(t, scheduler.AddRecurringTask(intTask{Identifier: 1}, schedule))
+
+	clock.Advance(59 * time.Minute)
+	_, ok := scheduler.TryGetTask()
+	assert.False(t, ok)
+
+	clock.Advance(time.Minute)
+	_, ok = scheduler.TryGetTask()
+	assert.True(t, ok)
+}
@@ -0,0 +1,616 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math/rand/v2"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go delayed_test.go fairness_test.go executor_test.go
+
+// Исполнитель по модели G-M-P из рантайма Go: каждый P (Worker) держит локальную
+// кольцевую очередь фиксированного размера, лишние задачи уходят в общую глобальную
+// очередь, а простаивающий P крадет половину очереди у другого.
+
+// Job - задача исполнителя (G); через w можно запустить новые задачи на том же P
+type Job func(w *Worker)
+
+// localQueueSize - размер локальной очереди, как у runq в рантайме
+const localQueueSize = 256
+
+// globalCheckPeriod - раз во столько задач P сначала проверяет глобальную очередь,
+// чтобы задачи в ней не голодали (в рантайме тоже 61)
+const globalCheckPeriod = 61
+
+var ErrExecutorClosed = errors.New("executor is closed")
+
+// localQueue - кольцевая очередь: добавляет только владелец (tail), забирают
+// владелец и воры (head через CAS)
+type localQueue struct {
+	head  atomic.Uint32
+	tail  atomic.Uint32
+	slots [localQueueSize]atomic.Pointer[Job]
+}
+
+// put - добавить задачу (только владелец), false - если очередь заполнена
+func (q *localQueue) put(job *Job) bool {
+	head, tail := q.head.Load(), q.tail.Load()
+	if tail-head >= localQueueSize {
+		return false
+	}
+
+	q.slots[tail%localQueueSize].Store(job)
+	q.tail.Store(tail + 1)
+	return true
+}
+
+// get - забрать задачу из головы очереди, nil - если очередь пуста
+func (q *localQueue) get() *Job {
+	for {
+		head, tail := q.head.Load(), q.tail.Load()
+		if head == tail {
+			return nil
+		}
+
+		job := q.slots[head%localQueueSize].Load()
+		if q.head.CompareAndSwap(head, head+1) {
+			return job
+		}
+	}
+}
+
+// takeHalf - забрать половину задач (только владелец) для переноса в глобальную очередь
+func (q *localQueue) takeHalf() []*Job {
+	for {
+		head, tail := q.head.Load(), q.tail.Load()
+		count := (tail - head) / 2
+		if count == 0 {
+			return nil
+		}
+
+		batch := make([]*Job, count)
+		for i := range batch {
+			batch[i] = q.slots[(head+uint32(i))%localQueueSize].Load()
+		}
+		if q.head.CompareAndSwap(head, head+count) {
+			return batch
+		}
+	}
+}
+
+// stealFrom - перенести половину задач жертвы в свою пустую очередь, вернуть их количество
+func (q *localQueue) stealFrom(victim *localQueue) int {
+	tail := q.tail.Load()
+	for {
+		head, victimTail := victim.head.Load(), victim.tail.Load()
+		count := victimTail - head
+		count -= count / 2
+		if count == 0 {
+			return 0
+		}
+		if count > localQueueSize/2 {
+			continue // head и tail прочитаны несогласованно
+		}
+
+		for i := uint32(0); i < count; i++ {
+			q.slots[(tail+i)%localQueueSize].Store(victim.slots[(head+i)%localQueueSize].Load())
+		}
+		if victim.head.CompareAndSwap(head, head+count) {
+			q.tail.Store(tail + count)
+			return int(count)
+		}
+	}
+}
+
+func (q *localQueue) len() int {
+	for {
+		head, tail := q.head.Load(), q.tail.Load()
+		if head == q.head.Load() {
+			return int(tail - head)
+		}
+	}
+}
+
+// WorkerStats - статистика одного P
+type WorkerStats struct {
+	Executed    uint64 // выполнено задач
+	Steals      uint64 // успешных краж
+	Stolen      uint64 // задач украдено у других P
+	GlobalTaken uint64 // задач взято из глобальной очереди
+	Overflows   uint64 // переносов половины локальной очереди в глобальную
+	Panics      uint64 // задач, завершившихся паникой
+	QueueLen    int    // текущая длина локальной очереди
+}
+
+type Worker struct {
+	id       int
+	executor *Executor
+	local    localQueue
+	ticks    uint64
+
+	executed    atomic.Uint64
+	steals      atomic.Uint64
+	stolen      atomic.Uint64
+	globalTaken atomic.Uint64
+	overflows   atomic.Uint64
+	panics      atomic.Uint64
+}
+
+type Executor struct {
+	workers []*Worker
+	onPanic PanicHandler
+
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	global   []*Job
+	closed   bool // новые задачи снаружи не принимаются
+	stopping bool // все задачи выполнены, P завершаются
+
+	idle      atomic.Int32 // P, которые собираются уснуть или спят
+	pending   sync.WaitGroup
+	workersWg sync.WaitGroup
+}
+
+// PanicHandler - получает значение паники задачи и стек горутины в момент паники
+type PanicHandler func(value any, stack []byte)
+
+type ExecutorOption func(*Executor)
+
+// WithPanicHandler - вместо записи в лог передавать паники задач в handler
+func WithPanicHandler(handler PanicHandler) ExecutorOption {
+	return func(e *Executor) {
+		e.onPanic = handler
+	}
+}
+
+func logPanic(value any, stack []byte) {
+	log.Printf("executor: job panicked: %v\n%s", value, stack)
+}
+
+// NewExecutor - запустить исполнитель с count P (по умолчанию GOMAXPROCS)
+func NewExecutor(count int, options ...ExecutorOption) *Executor {
+	if count <= 0 {
+		count = runtime.GOMAXPROCS(0)
+	}
+
+	e := &Executor{workers: make([]*Worker, count), onPanic: logPanic}
+	for _, option := range options {
+		option(e)
+	}
+	e.cond = sync.NewCond(&e.mutex)
+	for idx := range e.workers {
+		e.workers[idx] = &Worker{id: idx, executor: e}
+	}
+
+	e.workersWg.Add(count)
+	for _, worker := range e.workers {
+		go worker.run()
+	}
+
+	return e
+}
+
+// Submit - отправить задачу в глобальную очередь
+func (e *Executor) Submit(job Job) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.closed {
+		return ErrExecutorClosed
+	}
+
+	e.pending.Add(1)
+	e.global = append(e.global, &job)
+	e.cond.Signal()
+	return nil
+}
+
+// Close - дождаться выполнения всех задач, включая порожденные, и остановить P
+func (e *Executor) Close() {
+	e.mutex.Lock()
+	e.closed = true
+	e.mutex.Unlock()
+
+	e.pending.Wait()
+
+	e.mutex.Lock()
+	e.stopping = true
+	e.cond.Broadcast()
+	e.mutex.Unlock()
+
+	e.workersWg.Wait()
+}
+
+func (e *Executor) Stats() []WorkerStats {
+	stats := make([]WorkerStats, len(e.workers))
+	for idx, worker := range e.workers {
+		stats[idx] = WorkerStats{
+			Executed:    worker.executed.Load(),
+			Steals:      worker.steals.Load(),
+			Stolen:      worker.stolen.Load(),
+			GlobalTaken: worker.globalTaken.Load(),
+			Overflows:   worker.overflows.Load(),
+			Panics:      worker.panics.Load(),
+			QueueLen:    worker.local.len(),
+		}
+	}
+
+	return stats
+}
+
+func (e *Executor) GlobalQueueLen() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.global)
+}
+
+// wakeup - разбудить спящий P, если такой есть
+func (e *Executor) wakeup() {
+	if e.idle.Load() > 0 {
+		e.mutex.Lock()
+		e.cond.Signal()
+		e.mutex.Unlock()
+	}
+}
+
+// park - уснуть до появления работы, false - исполнитель останавливается
+func (e *Executor) park() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	// idle увеличивается до проверки очередей: либо мы увидим новую задачу,
+	// либо добавивший ее P увидит нас в idle и разбудит
+	e.idle.Add(1)
+	defer e.idle.Add(-1)
+
+	for !e.stopping {
+		if len(e.global) != 0 || e.hasLocalWork() {
+			return true
+		}
+		e.cond.Wait()
+	}
+
+	return false
+}
+
+func (e *Executor) hasLocalWork() bool {
+	for _, worker := range e.workers {
+		if worker.local.len() != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// takeGlobal - взять пачку задач из глобальной очереди (вызывается с захваченным мьютексом)
+func (e *Executor) takeGlobal(limit int) []*Job {
+	count := min(len(e.global), len(e.global)/len(e.workers)+1, limit)
+	batch := e.global[:count:count]
+	e.global = e.global[count:]
+	if len(e.global) == 0 {
+		e.global = nil // отпускаем старый массив
+	}
+
+	return batch
+}
+
+func (w *Worker) ID() int {
+	return w.id
+}
+
+// Go - запустить задачу на текущем P, как оператор go внутри горутины
+func (w *Worker) Go(job Job) {
+	w.executor.pending.Add(1)
+
+	for !w.local.put(&job) {
+		batch := w.local.takeHalf()
+		if batch == nil {
+			continue // очередь успели опустошить воры
+		}
+
+		w.overflows.Add(1)
+		w.executor.mutex.Lock()
+		w.executor.global = append(w.executor.global, batch...)
+		w.executor.global = append(w.executor.global, &job)
+		w.executor.cond.Signal()
+		w.executor.mutex.Unlock()
+		return
+	}
+
+	w.executor.wakeup()
+}
+
+func (w *Worker) run() {
+	defer w.executor.workersWg.Done()
+
+	for {
+		job := w.findJob()
+		if job == nil {
+			return
+		}
+
+		w.execute(job)
+		w.executed.Add(1)
+		w.executor.pending.Done()
+	}
+}
+
+// execute - паника задачи не должна останавливать P и весь процесс
+func (w *Worker) execute(job *Job) {
+	defer func() {
+		if value := recover(); value != nil {
+			w.panics.Add(1)
+			w.executor.onPanic(value, debug.Stack())
+		}
+	}()
+
+	(*job)(w)
+}
+
+func (w *Worker) findJob() *Job {
+	for {
+		w.ticks++
+		if w.ticks%globalCheckPeriod == 0 {
+			if job := w.getGlobal(1); job != nil {
+				return job
+			}
+		}
+
+		if job := w.local.get(); job != nil {
+			return job
+		}
+		if job := w.getGlobal(localQueueSize / 2); job != nil {
+			return job
+		}
+		if job := w.steal(); job != nil {
+			return job
+		}
+		if !w.executor.park() {
+			return nil
+		}
+	}
+}
+
+// getGlobal - взять задачу из глобальной очереди и переложить еще несколько в локальную
+func (w *Worker) getGlobal(limit int) *Job {
+	w.executor.mutex.Lock()
+	batch := w.executor.takeGlobal(limit)
+	w.executor.mutex.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	w.globalTaken.Add(uint64(len(batch)))
+	for _, job := range batch[1:] {
+		w.local.put(job) // локальная очередь пуста или почти пуста, место есть
+	}
+	if len(batch) > 1 {
+		w.executor.wakeup()
+	}
+
+	return batch[0]
+}
+
+// steal - украсть половину очереди у одного из P, начиная со случайного
+func (w *Worker) steal() *Job {
+	workers := w.executor.workers
+	offset := rand.IntN(len(workers))
+	for i := range workers {
+		victim := workers[(offset+i)%len(workers)]
+		if victim == w {
+			continue
+		}
+
+		if count := w.local.stealFrom(&victim.local); count != 0 {
+			w.steals.Add(1)
+			w.stolen.Add(uint64(count))
+			return w.local.get()
+		}
+	}
+
+	return nil
+}
+
+func TestLocalQueue(t *testing.T) {
+	var queue localQueue
+	jobs := make([]Job, localQueueSize+1)
+	for idx := 0; idx < localQueueSize; idx++ {
+		assert.True(t, queue.put(&jobs[idx]))
+	}
+	assert.False(t, queue.put(&jobs[localQueueSize]))
+	assert.Equal(t, localQueueSize, queue.len())
+
+	assert.Same(t, &jobs[0], queue.get())
+	assert.Same(t, &jobs[1], queue.get())
+
+	var thief localQueue
+	assert.Equal(t, 127, thief.stealFrom(&queue))
+	assert.Equal(t, 127, thief.len())
+	assert.Equal(t, 127, queue.len())
+	assert.Same(t, &jobs[2], thief.get())
+	assert.Same(t, &jobs[129], queue.get())
+
+	batch := queue.takeHalf()
+	assert.Len(t, batch, 63)
+	assert.Same(t, &jobs[130], batch[0])
+	assert.Equal(t, 63, queue.len())
+
+	var empty localQueue
+	assert.Nil(t, empty.get())
+	assert.Zero(t, thief.stealFrom(&empty))
+	assert.Nil(t, empty.takeHalf())
+}
+
+func TestExecutor(t *testing.T) {
+	executor := NewExecutor(4)
+
+	var counter atomic.Int64
+	for i := 0; i < 10000; i++ {
+		assert.NoError(t, executor.Submit(func(*Worker) {
+			counter.Add(1)
+		}))
+	}
+
+	executor.Close()
+	assert.Equal(t, int64(10000), counter.Load())
+	assert.ErrorIs(t, executor.Submit(func(*Worker) {}), ErrExecutorClosed)
+
+	var executed uint64
+	for _, stats := range executor.Stats() {
+		executed += stats.Executed
+		assert.Zero(t, stats.QueueLen)
+	}
+	assert.Equal(t, uint64(10000), executed)
+	assert.Zero(t, executor.GlobalQueueLen())
+}
+
+// spawnTree - задачи порождают задачи на своем P, как рекурсивный параллельный алгоритм
+func spawnTree(counter *atomic.Int64, depth int) Job {
+	return func(w *Worker) {
+		if depth == 0 {
+			counter.Add(1)
+			return
+		}
+
+		w.Go(spawnTree(counter, depth-1))
+		w.Go(spawnTree(counter, depth-1))
+	}
+}
+
+func TestExecutorSpawn(t *testing.T) {
+	executor := NewExecutor(4)
+
+	var leaves atomic.Int64
+	assert.NoError(t, executor.Submit(spawnTree(&leaves, 14)))
+
+	executor.Close()
+	assert.Equal(t, int64(1<<14), leaves.Load())
+}
+
+func TestExecutorStealing(t *testing.T) {
+	const jobs = localQueueSize / 2
+	executor := NewExecutor(2)
+
+	// P с первой задачей заблокирован, пока порожденные им задачи не выполнятся,
+	// поэтому их может выполнить только второй P, и только украв их
+	var executed atomic.Int64
+	assert.NoError(t, executor.Submit(func(w *Worker) {
+		done := make(chan struct{})
+		for i := 0; i < jobs; i++ {
+			w.Go(func(*Worker) {
+				if executed.Add(1) == jobs {
+					close(done)
+				}
+			})
+		}
+		<-done
+	}))
+
+	executor.Close()
+
+	var steals, stolen, total uint64
+	for _, stats := range executor.Stats() {
+		steals += stats.Steals
+		stolen += stats.Stolen
+		total += stats.Executed
+		assert.Zero(t, stats.QueueLen)
+	}
+
+	assert.Equal(t, int64(jobs), executed.Load())
+	assert.Equal(t, uint64(jobs+1), total)
+	assert.Equal(t, uint64(jobs), stolen)
+	assert.NotZero(t, steals)
+}
+
+func TestExecutorOverflow(t *testing.T) {
+	// единственный P не может отдать задачи ворам, и локальная очередь переполняется
+	executor := NewExecutor(1)
+
+	var executed atomic.Int64
+	assert.NoError(t, executor.Submit(func(w *Worker) {
+		for i := 0; i < 2*localQueueSize; i++ {
+			w.Go(func(*Worker) { executed.Add(1) })
+		}
+	}))
+
+	executor.Close()
+
+	stats := executor.Stats()[0]
+	assert.Equal(t, int64(2*localQueueSize), executed.Load())
+	assert.NotZero(t, stats.Overflows)
+	assert.NotZero(t, stats.GlobalTaken)
+	assert.Zero(t, stats.Steals)
+}
+
+func TestExecutorPanic(t *testing.T) {
+	var mutex sync.Mutex
+	var panics []any
+	executor := NewExecutor(2, WithPanicHandler(func(value any, stack []byte) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		panics = append(panics, value)
+		assert.Contains(t, string(stack), "TestExecutorPanic")
+	}))
+
+	var executed atomic.Int64
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, executor.Submit(func(w *Worker) {
+			if i%5 == 0 {
+				panic(i)
+			}
+			executed.Add(1)
+		}))
+	}
+
+	executor.Close() // не зависает: задачи с паникой тоже считаются выполненными
+
+	var total uint64
+	for _, stats := range executor.Stats() {
+		total += stats.Panics
+	}
+
+	assert.Equal(t, int64(8), executed.Load())
+	assert.Equal(t, uint64(2), total)
+	assert.ElementsMatch(t, []any{0, 5}, panics)
+}
+
+func BenchmarkExecutor(b *testing.B) {
+	work := func() {
+		value := 0
+		for j := 0; j < 1000; j++ {
+			value += j % 7
+		}
+		_ = value
+	}
+
+	b.Run("goroutine per job", func(b *testing.B) {
+		var wg sync.WaitGroup
+		wg.Add(b.N)
+		for i := 0; i < b.N; i++ {
+			go func() {
+				defer wg.Done()
+				work()
+			}()
+		}
+		wg.Wait()
+	})
+
+	b.Run("executor", func(b *testing.B) {
+		executor := NewExecutor(0)
+		for i := 0; i < b.N; i++ {
+			_ = executor.Submit(func(*Worker) { work() })
+		}
+		executor.Close()
+	})
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go delayed_test.go fairness_test.go executor_test.go
+
+// schedulingPolicy - вычисляет rank задачи при постановке в очередь и смене приоритета
+type schedulingPolicy interface {
+	rank(priority int, enqueued time.Time) int64
+	reprioritize(from, to int, rank int64, enqueued time.Time) int64
+	dequeued(priority int, rank int64, served bool) // served - задача выдана, а не удалена
+}
+
+// strictPolicy - строгий порядок по приоритету, низкие приоритеты могут голодать
+type strictPolicy struct{}
+
+func (strictPolicy) rank(priority int, _ time.Time) int64 {
+	return int64(priority)
+}
+
+func (strictPolicy) reprioritize(_, to int, _ int64, _ time.Time) int64 {
+	return int64(to)
+}
+
+func (strictPolicy) dequeued(int, int64, bool) {}
+
+// agingPolicy - эффективный приоритет Priority + ожидание/step; он растет у всех задач
+// одинаково, поэтому порядок двух задач со временем не меняется, и куче достаточно
+// ключа, посчитанного при постановке в очередь
+type agingPolicy struct {
+	step  time.Duration
+	epoch time.Time
+}
+
+func (p *agingPolicy) rank(priority int, enqueued time.Time) int64 {
+	if p.epoch.IsZero() {
+		p.epoch = enqueued
+	}
+
+	return int64(priority)*int64(p.step) - int64(enqueued.Sub(p.epoch))
+}
+
+func (p *agingPolicy) reprioritize(_, to int, _ int64, enqueued time.Time) int64 {
+	return p.rank(to, enqueued)
+}
+
+func (p *agingPolicy) dequeued(int, int64, bool) {}
+
+// fairCost - виртуальная стоимость задачи с весом 1
+const fairCost = 1 << 20
+
+// fairPolicy - взвешенная справедливая очередь (WFQ): задачи с приоритетом P получают
+// долю выдачи, пропорциональную P, относительно других приоритетов
+type fairPolicy struct {
+	virtualTime int64
+	lastFinish  map[int]int64 // виртуальное время окончания последней задачи для приоритета
+	queued      map[int]int   // задач в очереди по приоритетам, пустые удаляются вместе с lastFinish
+}
+
+func newFairPolicy() *fairPolicy {
+	return &fairPolicy{
+		lastFinish: make(map[int]int64),
+		queued:     make(map[int]int),
+	}
+}
+
+func (p *fairPolicy) rank(priority int, _ time.Time) int64 {
+	finish := p.finish(priority)
+	p.lastFinish[priority] = finish
+	p.queued[priority]++
+
+	return -finish // раньше выдается задача с меньшим временем окончания
+}
+
+// reprioritize - стоимость задачи уже учтена в lastFinish прежнего приоритета,
+// поэтому новый приоритет определяет только ее место, но не сдвигает следующие задачи
+func (p *fairPolicy) reprioritize(from, to int, _ int64, _ time.Time) int64 {
+	p.leave(from)
+	p.queued[to]++
+
+	return -p.finish(to)
+}
+
+func (p *fairPolicy) dequeued(priority int, rank int64, served bool) {
+	if served {
+		p.virtualTime = max(p.virtualTime, -rank)
+	}
+
+	p.leave(priority)
+}
+
+func (p *fairPolicy) finish(priority int) int64 {
+	weight := int64(max(priority, 1))
+	return max(p.virtualTime, p.lastFinish[priority]) + fairCost/weight
+}
+
+// leave - приоритет без задач в очереди забывается, иначе произвольные приоритеты
+// (например, метки времени) копились бы в картах бесконечно
+func (p *fairPolicy) leave(priority int) {
+	if p.queued[priority]--; p.queued[priority] <= 0 {
+		delete(p.queued, priority)
+		delete(p.lastFinish, priority)
+	}
+}
+
+// WithAging - приоритет ожидающей задачи растет на единицу за каждый step
+func WithAging(step time.Duration) SchedulerOption {
+	if step <= 0 {
+		panic("non-positive aging step")
+	}
+
+	return func(c *schedulerConfig) {
+		c.newPolicy = func() schedulingPolicy { return &agingPolicy{step: step} }
+	}
+}
+
+// WithWeightedFair - приоритет задает вес, а не строгий порядок: задача с
+// приоритетом 100 выдается в среднем в 10 раз чаще задачи с приоритетом 10
+func WithWeightedFair() SchedulerOption {
+	return func(c *schedulerConfig) {
+		c.newPolicy = func() schedulingPolicy { return newFairPolicy() }
+	}
+}
+
+// WithBandWidth - ширина диапазона приоритетов для статистики ожидания
+func WithBandWidth(width int) SchedulerOption {
+	if width <= 0 {
+		panic("non-positive band width")
+	}
+
+	return func(c *schedulerConfig) {
+		c.bandWidth = width
+	}
+}
+
+// WaitStats - время ожидания в очереди выданных задач одного диапазона приоритетов
+type WaitStats struct {
+	Count     int
+	MaxWait   time.Duration
+	TotalWait time.Duration
+}
+
+func (w WaitStats) AverageWait() time.Duration {
+	if w.Count == 0 {
+		return 0
+	}
+
+	return w.TotalWait / time.Duration(w.Count)
+}
+
+// WaitStats - статистика по диапазонам, ключ - нижняя граница диапазона
+func (s *Scheduler[K, V]) WaitStats() map[int]WaitStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := make(map[int]WaitStats, len(s.waitStats))
+	for band, value := range s.waitStats {
+		stats[band] = value
+	}
+
+	return stats
+}
+
+func (s *Scheduler[K, V]) recordWait(priority int, wait time.Duration) {
+	band := priority / s.bandWidth
+	if priority%s.bandWidth < 0 {
+		band-- // округление вниз для отрицательных приоритетов
+	}
+	band *= s.bandWidth
+
+	stats := s.waitStats[band]
+	stats.Count++
+	stats.TotalWait += wait
+	stats.MaxWait = max(stats.MaxWait, wait)
+	s.waitStats[band] = stats
+}
+
+// starvationScenario - одна задача с приоритетом 10 и поток задач с приоритетом 100,
+// каждую секунду приходит и выдается одна новая задача; возвращает номер шага, на котором
+// была выдана задача с приоритетом 10 (0 - не была выдана)
+func starvationScenario(t *testing.T, scheduler *Scheduler[int, struct{}], clock *fakeClock, steps int) int {
+	assert.NoError(t, scheduler.AddTask(intTask{Identifier: 0, Priority: 10}))
+
+	for step := 1; step <= steps; step++ {
+		clock.Advance(time.Second)
+		assert.NoError(t, scheduler.AddTask(intTask{Identifier: step, Priority: 100}))
+
+		task, ok := scheduler.TryGetTask()
+		assert.True(t, ok)
+		if task.Identifier == 0 {
+			return step
+		}
+	}
+
+	return 0
+}
+
+func TestStarvation(t *testing.T) {
+	tests := map[string]struct {
+		options []SchedulerOption
+		served  int
+	}{
+		"test case with strict priorities": {
+			served: 0,
+		},
+		"test case with aging": {
+			options: []SchedulerOption{WithAging(time.Second)},
+			served:  90,
+		},
+		"test case with weighted fair queue": {
+			options: []SchedulerOption{WithWeightedFair()},
+			served:  11, // целочисленная стоимость 2^20/100 немного занижена
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clock := newFakeClock(clockStart)
+			scheduler := NewScheduler[int, struct{}](append(test.options, WithClock(clock))...)
+
+			served := starvationScenario(t, scheduler, clock, 200)
+			assert.Equal(t, test.served, served)
+
+			stats := scheduler.WaitStats()
+			if served == 0 {
+				assert.Zero(t, stats[10].Count)
+			} else {
+				assert.Equal(t, WaitStats{Count: 1, MaxWait: time.Duration(served) * time.Second,
+					TotalWait: time.Duration(served) * time.Second}, stats[10])
+			}
+		})
+	}
+}
+
+func TestWeightedFairShares(t *testing.T) {
+	clock := newFakeClock(clockStart)
+	scheduler := NewScheduler[int, struct{}](WithWeightedFair(), WithClock(clock))
+
+	id := 0
+	for i := 0; i < 100; i++ {
+		for _, priority := range []int{10, 30} {
+			id++
+			assert.NoError(t, scheduler.AddTask(intTask{Identifier: id, Priority: priority}))
+		}
+	}
+
+	counts := make(map[int]int)
+	for i := 0; i < 80; i++ {
+		task, _ := scheduler.TryGetTask()
+		counts[task.Priority]++
+	}
+
+	assert.Equal(t, map[int]int{10: 20, 30: 60}, counts)
+}
+
+func TestWaitStats(t *testing.T) {
+	clock := newFakeClock(clockStart)
+	scheduler := NewScheduler[int, struct{}](WithClock(clock), WithBandWidth(100))
+
+	assert.NoError(t, scheduler.AddTask(intTask{Identifier: 1, Priority: 150}))
+	assert.NoError(t, scheduler.AddTask(intTask{Identifier: 2, Priority: 120}))
+	assert.NoError(t, scheduler.AddTask(intTask{Identifier: 3, Priority: -5}))
+
+	clock.Advance(time.Second)
+	scheduler.TryGetTask()
+	clock.Advance(3 * time.Second)
+	scheduler.TryGetTask()
+	scheduler.TryGetTask()
+
+	stats := scheduler.WaitStats()
+	assert.Equal(t, WaitStats{Count: 2, MaxWait: 4 * time.Second, TotalWait: 5 * time.Second}, stats[100])
+	assert.Equal(t, 2500*time.Millisecond, stats[100].AverageWait())
+	assert.Equal(t, WaitStats{Count: 1, MaxWait: 4 * time.Second, TotalWait: 4 * time.Second}, stats[-100])
+}
+
+func TestWeightedFairPolicyState(t *testing.T) {
+	scheduler := NewScheduler[int, struct{}](WithWeightedFair())
+	policy := scheduler.policy.(*fairPolicy)
+
+	// приоритеты вроде меток времени не накапливаются после выдачи задач
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, scheduler.AddTask(intTask{Identifier: i, Priority: 1000 + i}))
+	}
+	assert.Len(t, policy.lastFinish, 100)
+
+	scheduler.Drain()
+	assert.Empty(t, policy.lastFinish)
+	assert.Empty(t, policy.queued)
+
+	assert.NoError(t, scheduler.AddTask(intTask{Identifier: 1, Priority: 10}))
+	assert.NoError(t, scheduler.AddTask(intTask{Identifier: 2, Priority: 20}))
+	charged := policy.lastFinish[20]
+
+	// смена приоритета не списывает стоимость задачи еще раз
+	scheduler.ChangeTaskPriority(1, 20)
+	assert.Equal(t, charged, policy.lastFinish[20])
+	assert.NotContains(t, policy.lastFinish, 10)
+	assert.Equal(t, map[int]int{20: 2}, policy.queued)
+
+	assert.True(t, scheduler.RemoveTask(1))
+	assert.True(t, scheduler.RemoveTask(2))
+	assert.Empty(t, policy.lastFinish)
+	assert.Empty(t, policy.queued)
+}
@@ -2,113 +2,585 @@ package main
 
 import (
 	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
-type Task struct {
-	Identifier int
+// go test -v homework_test.go delayed_test.go fairness_test.go executor_test.go
+
+// Task - задача с идентификатором K и полезной нагрузкой V
+type Task[K comparable, V any] struct {
+	Identifier K
 	Priority   int
+	Value      V
 	index      int
+	rank       int64     // порядок в куче по текущей политике: больше - раньше
+	enqueued   time.Time // момент попадания в очередь приоритетов
+	sequence   uint64    // при равном порядке задачи выдаются в порядке добавления
 }
 
-type TaskHeap []*Task
+// public - копия задачи без служебных полей
+func (t *Task[K, V]) public() Task[K, V] {
+	return Task[K, V]{
+		Identifier: t.Identifier,
+		Priority:   t.Priority,
+		Value:      t.Value,
+	}
+}
 
-func (h TaskHeap) Len() int           { return len(h) }
-func (h TaskHeap) Less(i, j int) bool { return h[i].Priority > h[j].Priority } // max-heap
-func (h TaskHeap) Swap(i, j int) {
-	h[i], h[j] = h[j], h[i]
-	h[i].index = i
-	h[j].index = j
+type TaskHeap[K comparable, V any] struct {
+	tasks []*Task[K, V]
+	less  func(a, b Task[K, V]) bool // если задан, порядок определяет он, а не rank
 }
 
-func (h *TaskHeap) Push(x interface{}) {
-	n := len(*h)
-	task := x.(*Task)
+func (h *TaskHeap[K, V]) Len() int { return len(h.tasks) }
+func (h *TaskHeap[K, V]) Less(i, j int) bool { // max-heap
+	a, b := h.tasks[i], h.tasks[j]
+	if h.less != nil {
+		if h.less(*a, *b) {
+			return true
+		}
+		if h.less(*b, *a) {
+			return false
+		}
+	} else if a.rank != b.rank {
+		return a.rank > b.rank
+	}
+	return a.sequence < b.sequence
+}
+func (h *TaskHeap[K, V]) Swap(i, j int) {
+	h.tasks[i], h.tasks[j] = h.tasks[j], h.tasks[i]
+	h.tasks[i].index = i
+	h.tasks[j].index = j
+}
+
+func (h *TaskHeap[K, V]) Push(x interface{}) {
+	n := len(h.tasks)
+	task := x.(*Task[K, V])
 	task.index = n
-	*h = append(*h, task)
+	h.tasks = append(h.tasks, task)
 }
 
-func (h *TaskHeap) Pop() interface{} {
-	old := *h
+func (h *TaskHeap[K, V]) Pop() interface{} {
+	old := h.tasks
 	n := len(old)
 	task := old[n-1]
 	old[n-1] = nil  // don't stop the GC from reclaiming the item eventually (comment from libs)
 	task.index = -1 // for safety (comment from libs)
-	*h = old[0 : n-1]
+	h.tasks = old[0 : n-1]
 	return task
 }
 
-type Scheduler struct {
-	taskHeap TaskHeap
-	taskMap  map[int]*Task
+var ErrDuplicateTask = errors.New("task is already scheduled")
+
+type Scheduler[K comparable, V any] struct {
+	mutex      sync.Mutex
+	taskHeap   TaskHeap[K, V]
+	taskMap    map[K]*Task[K, V]
+	delayed    delayedHeap[K, V] // отложенные и периодические задачи по времени готовности
+	delayedMap map[K]*delayedTask[K, V]
+	clock      Clock
+	policy     schedulingPolicy
+	bandWidth  int
+	waitStats  map[int]WaitStats
+	sequence   uint64
+	notify     chan struct{} // закрывается при добавлении задачи, будит всех ожидающих
 }
 
-func NewScheduler() Scheduler {
-	return Scheduler{
-		taskMap: make(map[int]*Task),
+// schedulerConfig - настройки, не зависящие от типов задач
+type schedulerConfig struct {
+	clock     Clock
+	newPolicy func() schedulingPolicy
+	bandWidth int
+}
+
+type SchedulerOption func(*schedulerConfig)
+
+// WithClock - источник времени для отложенных задач (по умолчанию системные часы)
+func WithClock(clock Clock) SchedulerOption {
+	return func(c *schedulerConfig) {
+		c.clock = clock
 	}
 }
 
-// AddTask- запланировать задачу
-func (s *Scheduler) AddTask(task Task) {
-	t := Task{
-		Identifier: task.Identifier,
-		Priority:   task.Priority,
+// NewScheduler - планировщик, выдающий задачи по Priority с учетом политики
+func NewScheduler[K comparable, V any](options ...SchedulerOption) *Scheduler[K, V] {
+	config := schedulerConfig{
+		clock:     realClock{},
+		newPolicy: func() schedulingPolicy { return strictPolicy{} },
+		bandWidth: 10,
+	}
+	for _, option := range options {
+		option(&config)
+	}
+
+	return &Scheduler[K, V]{
+		taskMap:    make(map[K]*Task[K, V]),
+		delayedMap: make(map[K]*delayedTask[K, V]),
+		clock:      config.clock,
+		policy:     config.newPolicy(),
+		bandWidth:  config.bandWidth,
+		waitStats:  make(map[int]WaitStats),
+		notify:     make(chan struct{}),
 	}
+}
+
+// NewSchedulerFunc - планировщик с собственным порядком: less(a, b) - a выдается раньше b;
+// политики WithAging и WithWeightedFair при этом не действуют
+func NewSchedulerFunc[K comparable, V any](less func(a, b Task[K, V]) bool, options ...SchedulerOption) *Scheduler[K, V] {
+	s := NewScheduler[K, V](options...)
+	s.taskHeap.less = less
+	return s
+}
+
+// AddTask - запланировать задачу, ошибка ErrDuplicateTask, если задача
+// с таким идентификатором уже запланирована
+func (s *Scheduler[K, V]) AddTask(task Task[K, V]) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.contains(task.Identifier) {
+		return fmt.Errorf("%w: %v", ErrDuplicateTask, task.Identifier)
+	}
+
+	s.pushTask(task)
+	return nil
+}
+
+// UpsertTask - запланировать задачу или обновить приоритет и нагрузку уже запланированной
+func (s *Scheduler[K, V]) UpsertTask(task Task[K, V]) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.contains(task.Identifier) {
+		s.changeTask(task.Identifier, func(existing *Task[K, V]) {
+			existing.Priority = task.Priority
+			existing.Value = task.Value
+		})
+		return
+	}
+
+	s.pushTask(task)
+}
+
+func (s *Scheduler[K, V]) pushTask(task Task[K, V]) {
+	t := task.public()
+	t.enqueued = s.clock.Now()
+	t.sequence = s.sequence
+	t.rank = s.policy.rank(t.Priority, t.enqueued)
+	s.sequence++
 
 	s.taskMap[task.Identifier] = &t
 	heap.Push(&s.taskHeap, &t)
+	s.wakeUp()
 }
 
-// ChangeTaskPriority - изменить приоритет задачи по идентификатору
-func (s *Scheduler) ChangeTaskPriority(taskID int, newPriority int) {
+func (s *Scheduler[K, V]) wakeUp() {
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// RemoveTask - отменить запланированную задачу, false - если ее нет
+func (s *Scheduler[K, V]) RemoveTask(taskID K) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	removed := false
 	if task, ok := s.taskMap[taskID]; ok {
+		heap.Remove(&s.taskHeap, task.index)
+		delete(s.taskMap, taskID)
+		s.policy.dequeued(task.Priority, task.rank, false)
+		removed = true
+	}
+	if task, ok := s.delayedMap[taskID]; ok {
+		heap.Remove(&s.delayed, task.index)
+		delete(s.delayedMap, taskID)
+		removed = true
+	}
+
+	return removed
+}
+
+// Contains - запланирована ли задача с таким идентификатором
+func (s *Scheduler[K, V]) Contains(taskID K) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.contains(taskID)
+}
+
+func (s *Scheduler[K, V]) contains(taskID K) bool {
+	_, ready := s.taskMap[taskID]
+	_, delayed := s.delayedMap[taskID]
+	return ready || delayed
+}
+
+// Len - количество запланированных задач, включая еще не готовые отложенные;
+// периодическая задача, уже попавшая в очередь, считается один раз
+func (s *Scheduler[K, V]) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pending := s.taskHeap.Len()
+	for identifier := range s.delayedMap {
+		if _, queued := s.taskMap[identifier]; !queued {
+			pending++
+		}
+	}
+
+	return pending
+}
+
+// ChangeTaskPriority - изменить приоритет задачи по идентификатору
+func (s *Scheduler[K, V]) ChangeTaskPriority(taskID K, newPriority int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.changeTask(taskID, func(task *Task[K, V]) {
 		task.Priority = newPriority
-		heap.Fix(&s.taskHeap, task.index)
+	})
+}
+
+// changeTask - изменить задачу в очереди и в отложенных и восстановить порядок кучи
+func (s *Scheduler[K, V]) changeTask(taskID K, change func(task *Task[K, V])) {
+	if task, ok := s.taskMap[taskID]; ok {
+		previous := task.Priority
+		change(task)
+		if task.Priority != previous {
+			task.rank = s.policy.reprioritize(previous, task.Priority, task.rank, task.enqueued)
+		}
+		heap.Fix(&s.taskHeap, task.index) // компаратор NewSchedulerFunc может зависеть от Value
+	}
+	if task, ok := s.delayedMap[taskID]; ok {
+		change(&task.task)
 	}
 }
 
-// GetTask - получить задачу с наибольшим приоритетом для исполнения
-func (s *Scheduler) GetTask() Task {
-	if len(s.taskHeap) == 0 {
-		return Task{}
+// GetTask - получить задачу с наибольшим приоритетом, ожидая ее появления
+// до отмены контекста
+func (s *Scheduler[K, V]) GetTask(ctx context.Context) (Task[K, V], error) {
+	for {
+		s.mutex.Lock()
+		now := s.clock.Now()
+		s.promoteDue(now)
+		if s.taskHeap.Len() != 0 {
+			task := s.popTask()
+			s.mutex.Unlock()
+			return task, nil
+		}
+
+		notify := s.notify
+		var timer Timer
+		var due <-chan time.Time
+		if len(s.delayed) != 0 {
+			timer = s.clock.NewTimer(s.delayed[0].notBefore.Sub(now))
+			due = timer.C()
+		}
+		s.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return Task[K, V]{}, ctx.Err()
+		case <-notify:
+			// задачу мог забрать другой потребитель - проверяем очередь заново
+		case <-due:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
 	}
-	task := heap.Pop(&s.taskHeap).(*Task)
-	delete(s.taskMap, task.Identifier)
-	return Task{
-		Identifier: task.Identifier,
-		Priority:   task.Priority,
+}
+
+// TryGetTask - получить задачу с наибольшим приоритетом без ожидания
+func (s *Scheduler[K, V]) TryGetTask() (Task[K, V], bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.promoteDue(s.clock.Now())
+	if s.taskHeap.Len() == 0 {
+		return Task[K, V]{}, false
+	}
+
+	return s.popTask(), true
+}
+
+// Peek - задача, которая будет выдана следующей, без удаления из очереди
+func (s *Scheduler[K, V]) Peek() (Task[K, V], bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.promoteDue(s.clock.Now())
+	if s.taskHeap.Len() == 0 {
+		return Task[K, V]{}, false
 	}
+
+	return s.taskHeap.tasks[0].public(), true
 }
 
+// Drain - забрать все готовые задачи в порядке выдачи; отложенные и периодические
+// задачи, срок которых еще не наступил, остаются в планировщике
+func (s *Scheduler[K, V]) Drain() []Task[K, V] {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.promoteDue(s.clock.Now())
+	tasks := make([]Task[K, V], 0, s.taskHeap.Len())
+	for s.taskHeap.Len() != 0 {
+		tasks = append(tasks, s.popTask())
+	}
+
+	return tasks
+}
+
+func (s *Scheduler[K, V]) popTask() Task[K, V] {
+	task := heap.Pop(&s.taskHeap).(*Task[K, V])
+	delete(s.taskMap, task.Identifier)
+
+	s.policy.dequeued(task.Priority, task.rank, true)
+	s.recordWait(task.Priority, s.clock.Now().Sub(task.enqueued))
+
+	return task.public()
+}
+
+// intTask - задача без полезной нагрузки, как в исходном планировщике
+type intTask = Task[int, struct{}]
+
 func TestTrace(t *testing.T) {
-	task1 := Task{Identifier: 1, Priority: 10}
-	task2 := Task{Identifier: 2, Priority: 20}
-	task3 := Task{Identifier: 3, Priority: 30}
-	task4 := Task{Identifier: 4, Priority: 40}
-	task5 := Task{Identifier: 5, Priority: 50}
-
-	scheduler := NewScheduler()
-	scheduler.AddTask(task1)
-	scheduler.AddTask(task2)
-	scheduler.AddTask(task3)
-	scheduler.AddTask(task4)
-	scheduler.AddTask(task5)
-
-	task := scheduler.GetTask()
+	task1 := intTask{Identifier: 1, Priority: 10}
+	task2 := intTask{Identifier: 2, Priority: 20}
+	task3 := intTask{Identifier: 3, Priority: 30}
+	task4 := intTask{Identifier: 4, Priority: 40}
+	task5 := intTask{Identifier: 5, Priority: 50}
+
+	scheduler := NewScheduler[int, struct{}]()
+	assert.NoError(t, scheduler.AddTask(task1))
+	assert.NoError(t, scheduler.AddTask(task2))
+	assert.NoError(t, scheduler.AddTask(task3))
+	assert.NoError(t, scheduler.AddTask(task4))
+	assert.NoError(t, scheduler.AddTask(task5))
+
+	task, _ := scheduler.TryGetTask()
 	assert.Equal(t, task5, task)
 
-	task = scheduler.GetTask()
+	task, _ = scheduler.TryGetTask()
 	assert.Equal(t, task4, task)
 
 	scheduler.ChangeTaskPriority(1, 100)
 
-	task = scheduler.GetTask()
+	task, _ = scheduler.TryGetTask()
 	task1.Priority = 100
 	assert.Equal(t, task1, task)
 
-	task = scheduler.GetTask()
+	task, _ = scheduler.TryGetTask()
 	assert.Equal(t, task3, task)
 }
+
+func TestTryGetTask(t *testing.T) {
+	scheduler := NewScheduler[int, struct{}]()
+
+	task, ok := scheduler.TryGetTask()
+	assert.False(t, ok)
+	assert.Equal(t, intTask{}, task)
+
+	assert.NoError(t, scheduler.AddTask(intTask{Identifier: 1, Priority: 10}))
+	task, ok = scheduler.TryGetTask()
+	assert.True(t, ok)
+	assert.Equal(t, intTask{Identifier: 1, Priority: 10}, task)
+}
+
+func TestGetTaskBlocking(t *testing.T) {
+	scheduler := NewScheduler[int, struct{}]()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := scheduler.GetTask(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = scheduler.AddTask(intTask{Identifier: 1, Priority: 10})
+	}()
+
+	task, err := scheduler.GetTask(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, intTask{Identifier: 1, Priority: 10}, task)
+}
+
+func TestSchedulerConcurrent(t *testing.T) {
+	const producers = 4
+	const consumers = 4
+	const tasksPerProducer = 1000
+
+	scheduler := NewScheduler[int, struct{}]()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var received atomic.Int32
+	seen := make([]atomic.Bool, producers*tasksPerProducer)
+
+	var consumersWg sync.WaitGroup
+	consumersWg.Add(consumers)
+	for i := 0; i < consumers; i++ {
+		go func() {
+			defer consumersWg.Done()
+			for {
+				task, err := scheduler.GetTask(ctx)
+				if err != nil {
+					return
+				}
+				assert.False(t, seen[task.Identifier].Swap(true), "task %d received twice", task.Identifier)
+				if received.Add(1) == producers*tasksPerProducer {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	var producersWg sync.WaitGroup
+	producersWg.Add(producers)
+	for i := 0; i < producers; i++ {
+		go func(base int) {
+			defer producersWg.Done()
+			for j := 0; j < tasksPerProducer; j++ {
+				id := base*tasksPerProducer + j
+				assert.NoError(t, scheduler.AddTask(intTask{Identifier: id, Priority: id % 7}))
+				if j%10 == 0 {
+					scheduler.ChangeTaskPriority(id, 100)
+				}
+			}
+		}(i)
+	}
+
+	producersWg.Wait()
+	consumersWg.Wait()
+	assert.Equal(t, int32(producers*tasksPerProducer), received.Load())
+}
+
+func TestRemoveAndDuplicates(t *testing.T) {
+	scheduler := NewScheduler[int, struct{}]()
+	for id := 1; id <= 5; id++ {
+		assert.NoError(t, scheduler.AddTask(intTask{Identifier: id, Priority: id * 10}))
+	}
+
+	err := scheduler.AddTask(intTask{Identifier: 3, Priority: 100})
+	assert.ErrorIs(t, err, ErrDuplicateTask)
+	assert.Equal(t, 5, scheduler.Len())
+
+	assert.True(t, scheduler.Contains(5))
+	assert.True(t, scheduler.RemoveTask(5))
+	assert.False(t, scheduler.RemoveTask(5))
+	assert.False(t, scheduler.Contains(5))
+	assert.True(t, scheduler.RemoveTask(2))
+	assert.Equal(t, 3, scheduler.Len())
+
+	scheduler.UpsertTask(intTask{Identifier: 1, Priority: 100})
+	scheduler.UpsertTask(intTask{Identifier: 6, Priority: 35})
+	assert.Equal(t, 4, scheduler.Len())
+
+	// ни призраков в куче, ни потерянных записей в карте
+	var identifiers []int
+	for {
+		task, ok := scheduler.TryGetTask()
+		if !ok {
+			break
+		}
+		identifiers = append(identifiers, task.Identifier)
+		assert.False(t, scheduler.Contains(task.Identifier))
+	}
+
+	assert.Equal(t, []int{1, 4, 6, 3}, identifiers)
+	assert.Equal(t, 0, scheduler.Len())
+	assert.Empty(t, scheduler.taskMap)
+}
+
+type Deployment struct {
+	Service  string
+	Deadline time.Time
+}
+
+func TestGenericPayload(t *testing.T) {
+	scheduler := NewScheduler[string, Deployment]()
+	assert.NoError(t, scheduler.AddTask(Task[string, Deployment]{Identifier: "api", Priority: 10, Value: Deployment{Service: "api"}}))
+	assert.NoError(t, scheduler.AddTask(Task[string, Deployment]{Identifier: "db", Priority: 20, Value: Deployment{Service: "db"}}))
+	assert.ErrorIs(t, scheduler.AddTask(Task[string, Deployment]{Identifier: "db"}), ErrDuplicateTask)
+
+	scheduler.UpsertTask(Task[string, Deployment]{Identifier: "api", Priority: 30, Value: Deployment{Service: "api-v2"}})
+
+	task, ok := scheduler.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, "api-v2", task.Value.Service)
+	assert.Equal(t, 2, scheduler.Len())
+
+	task, err := scheduler.GetTask(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Task[string, Deployment]{Identifier: "api", Priority: 30, Value: Deployment{Service: "api-v2"}}, task)
+}
+
+func TestComparatorAndDrain(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// раньше выдается задача с ближайшим сроком, Priority не учитывается
+	scheduler := NewSchedulerFunc(func(a, b Task[string, Deployment]) bool {
+		return a.Value.Deadline.Before(b.Value.Deadline)
+	})
+
+	for idx, service := range []string{"api", "db", "cache", "queue"} {
+		deadline := start.Add(time.Duration(idx%3) * time.Hour)
+		assert.NoError(t, scheduler.AddTask(Task[string, Deployment]{
+			Identifier: service,
+			Priority:   idx,
+			Value:      Deployment{Service: service, Deadline: deadline},
+		}))
+	}
+
+	task, ok := scheduler.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, "api", task.Identifier)
+
+	var order []string
+	for _, task := range scheduler.Drain() {
+		order = append(order, task.Identifier)
+	}
+
+	// api и queue с одинаковым сроком выдаются в порядке добавления
+	assert.Equal(t, []string{"api", "queue", "db", "cache"}, order)
+	assert.Equal(t, 0, scheduler.Len())
+	assert.Empty(t, scheduler.Drain())
+
+	_, ok = scheduler.Peek()
+	assert.False(t, ok)
+}
+
+func TestUpsertValueWithComparator(t *testing.T) {
+	scheduler := NewSchedulerFunc(func(a, b Task[string, int]) bool {
+		return a.Value < b.Value
+	})
+
+	for idx, id := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, scheduler.AddTask(Task[string, int]{Identifier: id, Value: idx + 1}))
+	}
+
+	// приоритет не меняется, но порядок по Value должен обновиться
+	scheduler.UpsertTask(Task[string, int]{Identifier: "a", Value: 10})
+
+	task, ok := scheduler.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, "b", task.Identifier)
+
+	var order []string
+	for _, task := range scheduler.Drain() {
+		order = append(order, task.Identifier)
+	}
+	assert.Equal(t, []string{"b", "c", "d", "a"}, order)
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-// go test -v homework_test.go
+// go test -v homework_test.go ring_buffer_test.go lockfree_test.go window_test.go regions_test.go
 
 type CircularQueue struct {
 	values      []int
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -race -v homework_test.go ring_buffer_test.go lockfree_test.go window_test.go regions_test.go
+// go test -bench=. -cpu=4 homework_test.go ring_buffer_test.go lockfree_test.go window_test.go regions_test.go
+
+// cacheLineSize - head и tail разносятся по разным кэш-линиям, иначе производитель
+// и потребитель постоянно инвалидируют кэш друг друга (см. false_sharing)
+const cacheLineSize = 64
+
+type padding [cacheLineSize]byte
+
+// roundCapacity - емкость округляется до степени двойки, чтобы индекс считался маской
+func roundCapacity(capacity int) uint64 {
+	size := uint64(1)
+	for size < uint64(max(capacity, 1)) {
+		size <<= 1
+	}
+
+	return size
+}
+
+// SPSCRing - очередь для одного производителя и одного потребителя без ожиданий:
+// каждая операция завершается за конечное число шагов без CAS-циклов
+type SPSCRing[T any] struct {
+	_          padding
+	head       atomic.Uint64 // позиция чтения, меняет только потребитель
+	cachedTail uint64        // последний увиденный потребителем tail
+	_          padding
+	tail       atomic.Uint64 // позиция записи, меняет только производитель
+	cachedHead uint64        // последний увиденный производителем head
+	_          padding
+	mask       uint64
+	values     []T
+}
+
+func NewSPSCRing[T any](capacity int) *SPSCRing[T] {
+	size := roundCapacity(capacity)
+	return &SPSCRing[T]{
+		mask:   size - 1,
+		values: make([]T, size),
+	}
+}
+
+// Push - вызывается только из горутины производителя, false - если очередь заполнена
+func (r *SPSCRing[T]) Push(value T) bool {
+	tail := r.tail.Load()
+	if tail-r.cachedHead == uint64(len(r.values)) {
+		// чужую кэш-линию читаем, только когда очередь кажется заполненной
+		if r.cachedHead = r.head.Load(); tail-r.cachedHead == uint64(len(r.values)) {
+			return false
+		}
+	}
+
+	r.values[tail&r.mask] = value
+	r.tail.Store(tail + 1) // публикует значение для потребителя
+	return true
+}
+
+// Pop - вызывается только из горутины потребителя, false - если очередь пуста
+func (r *SPSCRing[T]) Pop() (T, bool) {
+	var zero T
+
+	head := r.head.Load()
+	if head == r.cachedTail {
+		if r.cachedTail = r.tail.Load(); head == r.cachedTail {
+			return zero, false
+		}
+	}
+
+	value := r.values[head&r.mask]
+	r.values[head&r.mask] = zero
+	r.head.Store(head + 1) // освобождает ячейку для производителя
+	return value, true
+}
+
+func (r *SPSCRing[T]) Len() int {
+	return int(r.tail.Load() - r.head.Load())
+}
+
+func (r *SPSCRing[T]) Cap() int {
+	return len(r.values)
+}
+
+// mpmcSlot - sequence показывает, чей сейчас ход: равен позиции - можно писать,
+// позиции + 1 - можно читать
+type mpmcSlot[T any] struct {
+	sequence atomic.Uint64
+	value    T
+}
+
+// MPMCRing - ограниченная очередь для многих производителей и потребителей
+// (алгоритм Дмитрия Вьюкова с порядковым номером в каждой ячейке)
+type MPMCRing[T any] struct {
+	_     padding
+	head  atomic.Uint64 // следующая позиция чтения
+	_     padding
+	tail  atomic.Uint64 // следующая позиция записи
+	_     padding
+	mask  uint64
+	slots []mpmcSlot[T]
+}
+
+func NewMPMCRing[T any](capacity int) *MPMCRing[T] {
+	size := roundCapacity(capacity)
+	ring := &MPMCRing[T]{
+		mask:  size - 1,
+		slots: make([]mpmcSlot[T], size),
+	}
+	for idx := range ring.slots {
+		ring.slots[idx].sequence.Store(uint64(idx))
+	}
+
+	return ring
+}
+
+// Push - false, если очередь заполнена
+func (r *MPMCRing[T]) Push(value T) bool {
+	position := r.tail.Load()
+	for {
+		slot := &r.slots[position&r.mask]
+		diff := int64(slot.sequence.Load() - position)
+		switch {
+		case diff == 0:
+			if r.tail.CompareAndSwap(position, position+1) {
+				slot.value = value
+				slot.sequence.Store(position + 1)
+				return true
+			}
+			position = r.tail.Load()
+		case diff < 0:
+			return false // ячейку еще не освободил потребитель с прошлого круга
+		default:
+			position = r.tail.Load() // позицию уже занял другой производитель
+		}
+	}
+}
+
+// Pop - false, если очередь пуста
+func (r *MPMCRing[T]) Pop() (T, bool) {
+	var zero T
+
+	position := r.head.Load()
+	for {
+		slot := &r.slots[position&r.mask]
+		diff := int64(slot.sequence.Load() - (position + 1))
+		switch {
+		case diff == 0:
+			if r.head.CompareAndSwap(position, position+1) {
+				value := slot.value
+				slot.value = zero
+				slot.sequence.Store(position + r.mask + 1) // ячейка свободна для следующего круга
+				return value, true
+			}
+			position = r.head.Load()
+		case diff < 0:
+			return zero, false // производитель еще не записал значение
+		default:
+			position = r.head.Load()
+		}
+	}
+}
+
+func (r *MPMCRing[T]) Cap() int {
+	return len(r.slots)
+}
+
+func TestSPSCRing(t *testing.T) {
+	ring := NewSPSCRing[int](3)
+	assert.Equal(t, 4, ring.Cap())
+
+	_, ok := ring.Pop()
+	assert.False(t, ok)
+
+	for i := 1; i <= 4; i++ {
+		assert.True(t, ring.Push(i))
+	}
+	assert.False(t, ring.Push(5))
+	assert.Equal(t, 4, ring.Len())
+
+	for i := 1; i <= 4; i++ {
+		value, ok := ring.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, i, value)
+	}
+	_, ok = ring.Pop()
+	assert.False(t, ok)
+}
+
+func TestSPSCRingConcurrent(t *testing.T) {
+	const count = 100000
+	ring := NewSPSCRing[int](64)
+
+	go func() {
+		for i := 0; i < count; {
+			if ring.Push(i) {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	for expected := 0; expected < count; {
+		value, ok := ring.Pop()
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+
+		if value != expected {
+			t.Fatalf("expected %d, got %d", expected, value)
+		}
+		expected++
+	}
+}
+
+func TestMPMCRing(t *testing.T) {
+	ring := NewMPMCRing[string](2)
+	assert.True(t, ring.Push("a"))
+	assert.True(t, ring.Push("b"))
+	assert.False(t, ring.Push("c"))
+
+	value, _ := ring.Pop()
+	assert.Equal(t, "a", value)
+	assert.True(t, ring.Push("c"))
+
+	value, _ = ring.Pop()
+	assert.Equal(t, "b", value)
+	value, _ = ring.Pop()
+	assert.Equal(t, "c", value)
+	_, ok := ring.Pop()
+	assert.False(t, ok)
+}
+
+func TestMPMCRingConcurrent(t *testing.T) {
+	const producers = 4
+	const consumers = 4
+	const perProducer = 20000
+
+	ring := NewMPMCRing[int](128)
+	seen := make([]atomic.Bool, producers*perProducer)
+
+	var producersWg sync.WaitGroup
+	producersWg.Add(producers)
+	for p := 0; p < producers; p++ {
+		go func(base int) {
+			defer producersWg.Done()
+			for i := 0; i < perProducer; {
+				if ring.Push(base + i) {
+					i++
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}(p * perProducer)
+	}
+
+	var received atomic.Int64
+	var consumersWg sync.WaitGroup
+	consumersWg.Add(consumers)
+	for c := 0; c < consumers; c++ {
+		go func() {
+			defer consumersWg.Done()
+			for received.Load() < producers*perProducer {
+				value, ok := ring.Pop()
+				if !ok {
+					runtime.Gosched()
+					continue
+				}
+
+				assert.False(t, seen[value].Swap(true), "value %d received twice", value)
+				received.Add(1)
+			}
+		}()
+	}
+
+	producersWg.Wait()
+	consumersWg.Wait()
+	assert.Equal(t, int64(producers*perProducer), received.Load())
+}
+
+// queue - общий интерфейс очередей для бенчмарков
+type queue interface {
+	Push(value int) bool
+	Pop() (int, bool)
+}
+
+type channelQueue chan int
+
+func (q channelQueue) Push(value int) bool {
+	select {
+	case q <- value:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q channelQueue) Pop() (int, bool) {
+	select {
+	case value := <-q:
+		return value, true
+	default:
+		return 0, false
+	}
+}
+
+type lockedCircularQueue struct {
+	mutex sync.Mutex
+	queue CircularQueue
+}
+
+func (q *lockedCircularQueue) Push(value int) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.queue.Push(value)
+}
+
+func (q *lockedCircularQueue) Pop() (int, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	value := q.queue.Front()
+	return value, q.queue.Pop()
+}
+
+// benchmarkQueue - producers горутин пишут и consumers горутин читают b.N значений
+func benchmarkQueue(b *testing.B, q queue, producers, consumers int) {
+	var wg sync.WaitGroup
+	var remaining atomic.Int64
+	remaining.Store(int64(b.N))
+
+	b.ResetTimer()
+
+	wg.Add(producers + consumers)
+	for p := 0; p < producers; p++ {
+		go func(count int) {
+			defer wg.Done()
+			for i := 0; i < count; {
+				if q.Push(i) {
+					i++
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}(b.N/producers + btoi(p < b.N%producers))
+	}
+
+	for c := 0; c < consumers; c++ {
+		go func() {
+			defer wg.Done()
+			for remaining.Load() > 0 {
+				if _, ok := q.Pop(); ok {
+					remaining.Add(-1)
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func btoi(value bool) int {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+func BenchmarkQueues(b *testing.B) {
+	const capacity = 1024
+
+	b.Run("SPSC ring", func(b *testing.B) {
+		benchmarkQueue(b, NewSPSCRing[int](capacity), 1, 1)
+	})
+	b.Run("SPSC channel", func(b *testing.B) {
+		benchmarkQueue(b, make(channelQueue, capacity), 1, 1)
+	})
+	b.Run("SPSC mutex", func(b *testing.B) {
+		benchmarkQueue(b, &lockedCircularQueue{queue: NewCircularQueue(capacity)}, 1, 1)
+	})
+
+	b.Run("MPMC ring", func(b *testing.B) {
+		benchmarkQueue(b, NewMPMCRing[int](capacity), 4, 4)
+	})
+	b.Run("MPMC channel", func(b *testing.B) {
+		benchmarkQueue(b, make(channelQueue, capacity), 4, 4)
+	})
+	b.Run("MPMC mutex", func(b *testing.B) {
+		benchmarkQueue(b, &lockedCircularQueue{queue: NewCircularQueue(capacity)}, 4, 4)
+	})
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go ring_buffer_test.go lockfree_test.go window_test.go regions_test.go
+
+var ErrRingBufferFull = errors.New("ring buffer is full")
+
+// ReadRegions - занятая часть массива по порядку: второй слайс непустой, только
+// если элементы переходят через конец массива; после чтения нужно вызвать CommitRead
+func (b *RingBuffer[T]) ReadRegions() (first, second []T) {
+	if b.count == 0 {
+		return nil, nil
+	}
+
+	end := b.front + b.count
+	if end <= len(b.values) {
+		return b.values[b.front:end:end], nil
+	}
+
+	end -= len(b.values)
+	return b.values[b.front:], b.values[:end:end]
+}
+
+// WriteRegions - свободная часть массива после последнего элемента; после записи
+// нужно вызвать CommitWrite
+func (b *RingBuffer[T]) WriteRegions() (first, second []T) {
+	free := len(b.values) - b.count
+	if free == 0 {
+		return nil, nil
+	}
+
+	start := (b.front + b.count) % len(b.values)
+	end := start + free
+	if end <= len(b.values) {
+		return b.values[start:end:end], nil
+	}
+
+	end -= len(b.values)
+	return b.values[start:], b.values[:end:end]
+}
+
+// CommitWrite - добавить в конец n элементов, записанных через WriteRegions
+func (b *RingBuffer[T]) CommitWrite(n int) {
+	if n < 0 || n > len(b.values)-b.count {
+		panic("ring buffer: commit write out of range")
+	}
+
+	b.count += n
+}
+
+// CommitRead - удалить из начала n элементов, прочитанных через ReadRegions
+func (b *RingBuffer[T]) CommitRead(n int) {
+	if n < 0 || n > b.count {
+		panic("ring buffer: commit read out of range")
+	}
+
+	first, second := b.ReadRegions()
+	cleared := min(n, len(first))
+	clear(first[:cleared]) // не держим ссылки для сборщика мусора
+	clear(second[:n-cleared])
+
+	b.count -= n
+	if b.count == 0 {
+		b.front = 0 // пустой буфер отдает для записи весь массив одним слайсом
+	} else {
+		b.front = b.index(n)
+	}
+}
+
+// PushMany - добавить элементы в конец, вернуть количество добавленных;
+// в режиме WithOverwrite добавляются все, а вытесненные передаются в WithEvictCallback
+func (b *RingBuffer[T]) PushMany(values []T) int {
+	b.reserve(len(values))
+
+	accepted := 0
+	if excess := len(values) - (len(b.values) - b.count); b.overwrite && excess > 0 && len(b.values) != 0 {
+		for i := min(excess, b.count); i > 0; i-- {
+			b.evict(b.Pop)
+		}
+
+		// начало входных данных вытеснило бы само себя, в буфер оно не попадает
+		if dropped := len(values) - len(b.values); dropped > 0 {
+			if b.onEvict != nil {
+				for _, value := range values[:dropped] {
+					b.onEvict(value)
+				}
+			}
+			values = values[dropped:]
+			accepted = dropped
+		}
+	}
+
+	first, second := b.WriteRegions()
+	copied := copy(first, values)
+	copied += copy(second, values[copied:])
+	b.CommitWrite(copied)
+
+	return accepted + copied
+}
+
+// PopMany - извлечь элементы из начала в dst, вернуть их количество
+func (b *RingBuffer[T]) PopMany(dst []T) int {
+	copied := b.copyTo(dst)
+	b.CommitRead(copied)
+	return copied
+}
+
+// FillFrom - прочитать данные из r прямо в свободное место буфера; второй вызов
+// Read (в часть после перехода через конец массива) делается, только если первый
+// заполнил свою часть целиком, поэтому короткое чтение не приводит к ожиданию
+func FillFrom(b *RingBuffer[byte], r io.Reader) (int, error) {
+	first, second := b.WriteRegions()
+	if len(first) == 0 {
+		return 0, ErrRingBufferFull
+	}
+
+	n, err := r.Read(first)
+	b.CommitWrite(n)
+	if n < len(first) || len(second) == 0 || err != nil {
+		return n, err
+	}
+
+	m, err := r.Read(second)
+	b.CommitWrite(m)
+	return n + m, err
+}
+
+func TestRegions(t *testing.T) {
+	buffer := NewRingBuffer[int](5)
+
+	first, second := buffer.ReadRegions()
+	assert.Empty(t, first)
+	assert.Empty(t, second)
+
+	first, second = buffer.WriteRegions()
+	assert.Len(t, first, 5)
+	assert.Empty(t, second)
+
+	assert.Equal(t, 4, buffer.PushMany([]int{1, 2, 3, 4}))
+	assert.Equal(t, 2, buffer.PopMany(make([]int, 2)))
+
+	// свободное место: индекс 4 и индексы 0, 1 после перехода через конец
+	first, second = buffer.WriteRegions()
+	assert.Len(t, first, 1)
+	assert.Len(t, second, 2)
+
+	first[0], second[0] = 5, 6
+	buffer.CommitWrite(2)
+
+	first, second = buffer.ReadRegions()
+	assert.Equal(t, []int{3, 4, 5}, first)
+	assert.Equal(t, []int{6}, second)
+	assert.Equal(t, []int{3, 4, 5, 6}, buffer.Snapshot())
+
+	// регионы ограничены по емкости, append не затрет соседние элементы
+	_ = append(first, 100)
+	assert.Equal(t, []int{3, 4, 5, 6}, buffer.Snapshot())
+
+	buffer.CommitRead(4)
+	assert.True(t, buffer.Empty())
+	assert.Equal(t, []int{0, 0, 0, 0, 0}, buffer.values)
+
+	first, second = buffer.WriteRegions()
+	assert.Len(t, first, 5) // после опустошения свободное место снова непрерывно
+	assert.Empty(t, second)
+
+	assert.Panics(t, func() { buffer.CommitRead(1) })
+	assert.Panics(t, func() { buffer.CommitWrite(6) })
+}
+
+func TestPushManyPopMany(t *testing.T) {
+	buffer := NewRingBuffer[int](4)
+	assert.Equal(t, 3, buffer.PushMany([]int{1, 2, 3}))
+	assert.Equal(t, 1, buffer.PushMany([]int{4, 5, 6}))
+
+	dst := make([]int, 3)
+	assert.Equal(t, 3, buffer.PopMany(dst))
+	assert.Equal(t, []int{1, 2, 3}, dst)
+
+	assert.Equal(t, 3, buffer.PushMany([]int{5, 6, 7, 8}))
+	assert.Equal(t, []int{4, 5, 6, 7}, buffer.Snapshot())
+
+	dst = make([]int, 10)
+	assert.Equal(t, 4, buffer.PopMany(dst))
+	assert.Equal(t, []int{4, 5, 6, 7}, dst[:4])
+	assert.Zero(t, buffer.PopMany(dst))
+
+	growing := NewRingBuffer[int](2, WithGrowth[int](DoubleGrowth))
+	assert.Equal(t, 5, growing.PushMany([]int{1, 2, 3, 4, 5}))
+	assert.Equal(t, 8, growing.Cap())
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, growing.Snapshot())
+
+	limited := NewRingBuffer[int](1, WithGrowth[int](func(capacity int) int { return min(capacity*2, 4) }))
+	assert.Equal(t, 4, limited.PushMany([]int{1, 2, 3, 4, 5, 6}))
+	assert.Equal(t, 4, limited.Cap())
+}
+
+func TestPushManyOverwrite(t *testing.T) {
+	var evicted []int
+	buffer := NewRingBuffer[int](4, WithOverwrite[int](), WithEvictCallback(func(value int) {
+		evicted = append(evicted, value)
+	}))
+
+	assert.Equal(t, 3, buffer.PushMany([]int{1, 2, 3}))
+	assert.Equal(t, 2, buffer.PushMany([]int{4, 5}))
+	assert.Equal(t, []int{2, 3, 4, 5}, buffer.Snapshot())
+	assert.Equal(t, []int{1}, evicted)
+
+	// результат тот же, что и при поэлементном Push
+	assert.Equal(t, 6, buffer.PushMany([]int{6, 7, 8, 9, 10, 11}))
+	assert.Equal(t, []int{8, 9, 10, 11}, buffer.Snapshot())
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7}, evicted)
+}
+
+// readFrames - разобрать поток кадров вида [длина][данные] через буфер из 8 байт
+func readFrames(t *testing.T, r io.Reader) []string {
+	buffer := NewRingBuffer[byte](8)
+	payload := make([]byte, 7)
+
+	var frames []string
+	for {
+		_, err := FillFrom(buffer, r)
+
+		for {
+			length, ok := buffer.Front()
+			if !ok || buffer.Len() < int(length)+1 {
+				break
+			}
+
+			buffer.CommitRead(1)
+			assert.Equal(t, int(length), buffer.PopMany(payload[:length]))
+			frames = append(frames, string(payload[:length]))
+		}
+
+		if err == io.EOF {
+			return frames
+		}
+		assert.NoError(t, err)
+	}
+}
+
+func TestFillFrom(t *testing.T) {
+	var stream bytes.Buffer
+	frames := []string{"hello", "", "go", "ring!!!", "a", "buffer"}
+	for _, frame := range frames {
+		stream.WriteByte(byte(len(frame)))
+		stream.WriteString(frame)
+	}
+
+	data := stream.Bytes()
+	readers := map[string]func() io.Reader{
+		"test case with whole reads":    func() io.Reader { return bytes.NewReader(data) },
+		"test case with one byte reads": func() io.Reader { return iotest.OneByteReader(bytes.NewReader(data)) },
+		"test case with half reads":     func() io.Reader { return iotest.HalfReader(bytes.NewReader(data)) },
+		"test case with data and EOF":   func() io.Reader { return iotest.DataErrReader(bytes.NewReader(data)) },
+	}
+
+	for name, reader := range readers {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, frames, readFrames(t, reader()))
+		})
+	}
+
+	full := NewRingBuffer[byte](1)
+	full.Push(1)
+	_, err := FillFrom(full, bytes.NewReader(data))
+	assert.ErrorIs(t, err, ErrRingBufferFull)
+}
+
+func TestFillFromWrapped(t *testing.T) {
+	buffer := NewRingBuffer[byte](8)
+	buffer.PushMany([]byte("abcdef"))
+	buffer.PopMany(make([]byte, 4))
+
+	// свободны индексы 6, 7 и 0-3: чтение заполняет обе части
+	n, err := FillFrom(buffer, bytes.NewReader([]byte("0123456789")))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, n)
+	assert.True(t, buffer.Full())
+	assert.Equal(t, []byte("ef012345"), buffer.Snapshot())
+
+	// короткое чтение в первую часть не продолжается второй
+	buffer.PopMany(make([]byte, 7))
+	n, err = FillFrom(buffer, iotest.OneByteReader(bytes.NewReader([]byte("xyz"))))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []byte("5x"), buffer.Snapshot())
+}
+
+func BenchmarkPushPop(b *testing.B) {
+	const batch = 512
+	values := make([]int, batch)
+	buffer := NewRingBuffer[int](1000)
+
+	b.Run("one by one", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, value := range values {
+				buffer.Push(value)
+			}
+			for range values {
+				buffer.Pop()
+			}
+		}
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			buffer.PushMany(values)
+			buffer.PopMany(values)
+		}
+	})
+}
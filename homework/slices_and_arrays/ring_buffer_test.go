@@ -0,0 +1,376 @@
+package main
+
+import (
+	"iter"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go ring_buffer_test.go lockfree_test.go window_test.go regions_test.go
+
+// GrowthPolicy - новая емкость заполненного буфера; если она не больше текущей,
+// буфер не растет и добавление не выполняется
+type GrowthPolicy func(capacity int) int
+
+// DoubleGrowth - удвоение емкости, как у append для небольших слайсов
+func DoubleGrowth(capacity int) int {
+	return max(2*capacity, 1)
+}
+
+type ringBufferConfig[T any] struct {
+	grow      GrowthPolicy
+	overwrite bool
+	onEvict   func(value T)
+}
+
+// RingBufferOption - опция параметризована типом элементов, поэтому callback
+// с другим типом аргумента не скомпилируется
+type RingBufferOption[T any] func(*ringBufferConfig[T])
+
+// WithGrowth - при заполнении переносить элементы в больший массив вместо отказа
+func WithGrowth[T any](policy GrowthPolicy) RingBufferOption[T] {
+	return func(c *ringBufferConfig[T]) {
+		c.grow = policy
+	}
+}
+
+// WithOverwrite - добавление в заполненный буфер, который не может расти, вытесняет
+// элемент с противоположного конца: Push - самый старый, PushFront - самый новый
+func WithOverwrite[T any]() RingBufferOption[T] {
+	return func(c *ringBufferConfig[T]) {
+		c.overwrite = true
+	}
+}
+
+// WithEvictCallback - вызывать callback для каждого элемента, вытесненного в режиме WithOverwrite
+func WithEvictCallback[T any](callback func(value T)) RingBufferOption[T] {
+	return func(c *ringBufferConfig[T]) {
+		c.onEvict = callback
+	}
+}
+
+// RingBuffer - кольцевой буфер (дек) с элементами любого типа
+type RingBuffer[T any] struct {
+	values []T
+	front  int // индекс первого элемента
+	count  int // количество элементов в буфере
+	grow   GrowthPolicy
+
+	overwrite bool
+	onEvict   func(value T)
+}
+
+func NewRingBuffer[T any](capacity int, options ...RingBufferOption[T]) *RingBuffer[T] {
+	var config ringBufferConfig[T]
+	for _, option := range options {
+		option(&config)
+	}
+
+	return &RingBuffer[T]{
+		values:    make([]T, capacity),
+		grow:      config.grow,
+		overwrite: config.overwrite,
+		onEvict:   config.onEvict,
+	}
+}
+
+// Push - добавить элемент в конец, false - если буфер заполнен и не может расти
+func (b *RingBuffer[T]) Push(value T) bool {
+	if b.Full() && !b.growUp() && !b.evict(b.Pop) {
+		return false
+	}
+
+	b.values[b.index(b.count)] = value
+	b.count++
+	return true
+}
+
+// PushFront - добавить элемент в начало
+func (b *RingBuffer[T]) PushFront(value T) bool {
+	if b.Full() && !b.growUp() && !b.evict(b.PopBack) {
+		return false
+	}
+
+	b.front = b.index(len(b.values) - 1)
+	b.values[b.front] = value
+	b.count++
+	return true
+}
+
+// Pop - извлечь элемент из начала
+func (b *RingBuffer[T]) Pop() (T, bool) {
+	var zero T
+	if b.Empty() {
+		return zero, false
+	}
+
+	value := b.values[b.front]
+	b.values[b.front] = zero // не держим ссылки для сборщика мусора
+	b.front = b.index(1)
+	b.count--
+	return value, true
+}
+
+// PopBack - извлечь элемент из конца
+func (b *RingBuffer[T]) PopBack() (T, bool) {
+	var zero T
+	if b.Empty() {
+		return zero, false
+	}
+
+	last := b.index(b.count - 1)
+	value := b.values[last]
+	b.values[last] = zero
+	b.count--
+	return value, true
+}
+
+func (b *RingBuffer[T]) Front() (T, bool) {
+	return b.At(0)
+}
+
+func (b *RingBuffer[T]) Back() (T, bool) {
+	return b.At(b.count - 1)
+}
+
+// At - i-й элемент от начала
+func (b *RingBuffer[T]) At(i int) (T, bool) {
+	if i < 0 || i >= b.count {
+		var zero T
+		return zero, false
+	}
+
+	return b.values[b.index(i)], true
+}
+
+// All - элементы от начала к концу вместе с их позициями
+func (b *RingBuffer[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := 0; i < b.count; i++ {
+			if !yield(i, b.values[b.index(i)]) {
+				return
+			}
+		}
+	}
+}
+
+func (b *RingBuffer[T]) Len() int {
+	return b.count
+}
+
+func (b *RingBuffer[T]) Cap() int {
+	return len(b.values)
+}
+
+func (b *RingBuffer[T]) Empty() bool {
+	return b.count == 0
+}
+
+func (b *RingBuffer[T]) Full() bool {
+	return b.count == len(b.values)
+}
+
+// Snapshot - элементы от начала к концу в новом слайсе
+func (b *RingBuffer[T]) Snapshot() []T {
+	values := make([]T, b.count)
+	b.copyTo(values)
+	return values
+}
+
+// copyTo - скопировать элементы по порядку в начало dst
+func (b *RingBuffer[T]) copyTo(dst []T) int {
+	first, second := b.ReadRegions()
+	copied := copy(dst, first)
+	return copied + copy(dst[copied:], second)
+}
+
+// evict - освободить место в заполненном буфере в режиме перезаписи
+func (b *RingBuffer[T]) evict(pop func() (T, bool)) bool {
+	if !b.overwrite {
+		return false
+	}
+
+	value, ok := pop()
+	if ok && b.onEvict != nil {
+		b.onEvict(value)
+	}
+
+	return ok
+}
+
+// index - индекс в массиве для позиции offset от начала
+func (b *RingBuffer[T]) index(offset int) int {
+	return (b.front + offset) % len(b.values)
+}
+
+// growUp - перенести элементы в больший массив, начиная с нулевого индекса
+func (b *RingBuffer[T]) growUp() bool {
+	return b.reserve(len(b.values) - b.count + 1)
+}
+
+// reserve - увеличить емкость так, чтобы поместилось еще n элементов; политика роста
+// применяется столько раз, сколько нужно, но элементы копируются один раз
+func (b *RingBuffer[T]) reserve(n int) bool {
+	capacity := len(b.values)
+	for b.grow != nil && capacity-b.count < n {
+		next := b.grow(capacity)
+		if next <= capacity {
+			break
+		}
+		capacity = next
+	}
+
+	if capacity == len(b.values) {
+		return capacity-b.count >= n
+	}
+
+	values := make([]T, capacity)
+	b.copyTo(values)
+	b.values = values
+	b.front = 0
+	return capacity-b.count >= n
+}
+
+func collect[T any](buffer *RingBuffer[T]) []T {
+	var values []T
+	for _, value := range buffer.All() {
+		values = append(values, value)
+	}
+
+	return values
+}
+
+func TestRingBuffer(t *testing.T) {
+	buffer := NewRingBuffer[string](3)
+
+	_, ok := buffer.Front()
+	assert.False(t, ok)
+	_, ok = buffer.Back()
+	assert.False(t, ok)
+	_, ok = buffer.Pop()
+	assert.False(t, ok)
+	_, ok = buffer.PopBack()
+	assert.False(t, ok)
+
+	assert.True(t, buffer.Push("b"))
+	assert.True(t, buffer.Push("c"))
+	assert.True(t, buffer.PushFront("a"))
+	assert.False(t, buffer.Push("d"))
+	assert.False(t, buffer.PushFront("d"))
+	assert.True(t, buffer.Full())
+	assert.Equal(t, []string{"a", "b", "c"}, collect(buffer))
+
+	front, _ := buffer.Front()
+	back, _ := buffer.Back()
+	assert.Equal(t, "a", front)
+	assert.Equal(t, "c", back)
+
+	value, ok := buffer.PopBack()
+	assert.True(t, ok)
+	assert.Equal(t, "c", value)
+	value, _ = buffer.Pop()
+	assert.Equal(t, "a", value)
+
+	assert.True(t, buffer.Push("x"))
+	assert.True(t, buffer.PushFront("y"))
+	assert.Equal(t, []string{"y", "b", "x"}, collect(buffer))
+
+	value, ok = buffer.At(1)
+	assert.True(t, ok)
+	assert.Equal(t, "b", value)
+	_, ok = buffer.At(3)
+	assert.False(t, ok)
+	_, ok = buffer.At(-1)
+	assert.False(t, ok)
+
+	// элементы, извлеченные из буфера, не удерживаются в массиве
+	for !buffer.Empty() {
+		buffer.Pop()
+	}
+	assert.Equal(t, []string{"", "", ""}, buffer.values)
+}
+
+func TestRingBufferGrowth(t *testing.T) {
+	buffer := NewRingBuffer[int](2, WithGrowth[int](DoubleGrowth))
+
+	assert.True(t, buffer.Push(1))
+	assert.True(t, buffer.Push(2))
+	buffer.Pop()
+	assert.True(t, buffer.Push(3)) // элементы переходят через конец массива
+
+	assert.True(t, buffer.Push(4))
+	assert.Equal(t, 4, buffer.Cap())
+	assert.Equal(t, []int{2, 3, 4, 0}, buffer.values) // переупорядочены с нуля
+
+	for i := 5; i <= 100; i++ {
+		assert.True(t, buffer.Push(i))
+		assert.True(t, buffer.PushFront(-i))
+	}
+	assert.Equal(t, 195, buffer.Len())
+	assert.Equal(t, 256, buffer.Cap())
+
+	values := collect(buffer)
+	assert.Equal(t, -100, values[0])
+	assert.Equal(t, 2, values[96])
+	assert.Equal(t, 100, values[194])
+
+	empty := NewRingBuffer[int](0, WithGrowth[int](DoubleGrowth))
+	assert.True(t, empty.PushFront(1))
+	assert.Equal(t, 1, empty.Cap())
+
+	limited := NewRingBuffer[int](1, WithGrowth[int](func(capacity int) int { return min(capacity*2, 2) }))
+	assert.True(t, limited.Push(1))
+	assert.True(t, limited.Push(2))
+	assert.False(t, limited.Push(3))
+}
+
+func TestRingBufferAll(t *testing.T) {
+	buffer := NewRingBuffer[int](4)
+	for i := 1; i <= 4; i++ {
+		buffer.Push(i * 10)
+	}
+
+	var positions []int
+	for idx, value := range buffer.All() {
+		if value > 30 {
+			break
+		}
+		positions = append(positions, idx)
+	}
+
+	assert.Equal(t, []int{0, 1, 2}, positions)
+}
+
+func TestRingBufferOverwrite(t *testing.T) {
+	var evicted []string
+	lines := NewRingBuffer[string](3, WithOverwrite[string](), WithEvictCallback(func(line string) {
+		evicted = append(evicted, line)
+	}))
+
+	for _, line := range []string{"one", "two", "three", "four", "five"} {
+		assert.True(t, lines.Push(line))
+	}
+	assert.Equal(t, []string{"three", "four", "five"}, lines.Snapshot())
+	assert.Equal(t, []string{"one", "two"}, evicted)
+
+	assert.True(t, lines.PushFront("zero"))
+	assert.Equal(t, []string{"zero", "three", "four"}, lines.Snapshot())
+	assert.Equal(t, []string{"one", "two", "five"}, evicted)
+
+	// снимок не разделяет память с буфером
+	snapshot := lines.Snapshot()
+	snapshot[0] = "changed"
+	front, _ := lines.Front()
+	assert.Equal(t, "zero", front)
+
+	empty := NewRingBuffer[string](0, WithOverwrite[string]())
+	assert.False(t, empty.Push("value"))
+	assert.Empty(t, empty.Snapshot())
+
+	// рост имеет приоритет над перезаписью
+	growing := NewRingBuffer[int](1, WithOverwrite[int](), WithGrowth[int](DoubleGrowth))
+	growing.Push(1)
+	growing.Push(2)
+	assert.Equal(t, []int{1, 2}, growing.Snapshot())
+}
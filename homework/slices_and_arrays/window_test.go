@@ -0,0 +1,336 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go ring_buffer_test.go lockfree_test.go window_test.go regions_test.go
+
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// windowEntry - значение с порядковым номером, чтобы понять, покинуло ли оно окно
+type windowEntry[T Number] struct {
+	value T
+	index uint64
+}
+
+// windowSum - сумма значений окна в более широком типе, чем T: целые копятся
+// в int64 или uint64, поэтому окно из int8 не переполняется, а для чисел с
+// плавающей точкой бесконечности и NaN считаются отдельно от конечной суммы
+// (иначе после вытеснения Inf сумма навсегда осталась бы NaN), а конечная
+// часть складывается с компенсацией ошибки округления (алгоритм Ноймайера)
+type windowSum struct {
+	kind reflect.Kind
+
+	signed   int64
+	unsigned uint64
+
+	finite       float64
+	compensation float64
+	nans         int
+	positiveInfs int
+	negativeInfs int
+}
+
+func (s *windowSum) add(value float64, signed int64, unsigned uint64, delta int) {
+	switch {
+	case s.kind >= reflect.Int && s.kind <= reflect.Int64:
+		s.signed += int64(delta) * signed
+	case s.kind >= reflect.Uint && s.kind <= reflect.Uintptr:
+		if delta > 0 {
+			s.unsigned += unsigned
+		} else {
+			s.unsigned -= unsigned
+		}
+	case math.IsNaN(value):
+		s.nans += delta
+	case math.IsInf(value, 1):
+		s.positiveInfs += delta
+	case math.IsInf(value, -1):
+		s.negativeInfs += delta
+	default:
+		value *= float64(delta)
+		sum := s.finite + value
+		if math.Abs(s.finite) >= math.Abs(value) {
+			s.compensation += (s.finite - sum) + value
+		} else {
+			s.compensation += (value - sum) + s.finite
+		}
+		s.finite = sum
+	}
+}
+
+func (s *windowSum) value() float64 {
+	switch {
+	case s.kind >= reflect.Int && s.kind <= reflect.Int64:
+		return float64(s.signed)
+	case s.kind >= reflect.Uint && s.kind <= reflect.Uintptr:
+		return float64(s.unsigned)
+	case s.nans > 0 || (s.positiveInfs > 0 && s.negativeInfs > 0):
+		return math.NaN()
+	case s.positiveInfs > 0:
+		return math.Inf(1)
+	case s.negativeInfs > 0:
+		return math.Inf(-1)
+	default:
+		return s.finite + s.compensation
+	}
+}
+
+// RollingWindow - последние size значений с агрегатами: сумма и среднее за O(1),
+// минимум и максимум за амортизированное O(1) через монотонные деки; NaN ни с чем
+// не сравнивается, поэтому в деки не попадает, а учитывается счетчиком в sum
+type RollingWindow[T Number] struct {
+	values   *RingBuffer[T]
+	sum      windowSum
+	pushed   uint64                      // номер следующего значения
+	evicted  uint64                      // номер самого старого значения в окне
+	minimums *RingBuffer[windowEntry[T]] // значения возрастают от начала к концу
+	maximums *RingBuffer[windowEntry[T]] // значения убывают от начала к концу
+}
+
+func NewRollingWindow[T Number](size int) *RollingWindow[T] {
+	if size <= 0 {
+		panic("non-positive window size")
+	}
+
+	w := &RollingWindow[T]{
+		sum:      windowSum{kind: reflect.TypeFor[T]().Kind()},
+		minimums: NewRingBuffer[windowEntry[T]](size),
+		maximums: NewRingBuffer[windowEntry[T]](size),
+	}
+	w.values = NewRingBuffer[T](size, WithOverwrite[T](), WithEvictCallback(w.remove))
+
+	return w
+}
+
+// Push - добавить значение, самое старое покидает окно, если оно заполнено
+func (w *RollingWindow[T]) Push(value T) {
+	w.values.Push(value)
+	w.sum.add(float64(value), int64(value), uint64(value), 1)
+	if value != value {
+		w.pushed++ // NaN
+		return
+	}
+
+	// значения, которые не меньше нового, уже никогда не станут минимумом
+	for last, ok := w.minimums.Back(); ok && last.value >= value; last, ok = w.minimums.Back() {
+		w.minimums.PopBack()
+	}
+	for last, ok := w.maximums.Back(); ok && last.value <= value; last, ok = w.maximums.Back() {
+		w.maximums.PopBack()
+	}
+
+	entry := windowEntry[T]{value: value, index: w.pushed}
+	w.minimums.Push(entry)
+	w.maximums.Push(entry)
+	w.pushed++
+}
+
+func (w *RollingWindow[T]) remove(value T) {
+	w.sum.add(float64(value), int64(value), uint64(value), -1)
+
+	if first, ok := w.minimums.Front(); ok && first.index == w.evicted {
+		w.minimums.Pop()
+	}
+	if first, ok := w.maximums.Front(); ok && first.index == w.evicted {
+		w.maximums.Pop()
+	}
+	w.evicted++
+}
+
+func (w *RollingWindow[T]) Len() int {
+	return w.values.Len()
+}
+
+// Sum - сумма значений окна; возвращается как float64, потому что в T она может
+// не поместиться
+func (w *RollingWindow[T]) Sum() float64 {
+	return w.sum.value()
+}
+
+// Mean - среднее значение, NaN для пустого окна
+func (w *RollingWindow[T]) Mean() float64 {
+	if w.values.Empty() {
+		return math.NaN()
+	}
+
+	return w.sum.value() / float64(w.values.Len())
+}
+
+// Min - минимум окна, NaN, пока в окне есть NaN
+func (w *RollingWindow[T]) Min() (T, bool) {
+	if w.sum.nans > 0 {
+		return nan[T](), true
+	}
+
+	entry, ok := w.minimums.Front()
+	return entry.value, ok
+}
+
+// Max - максимум окна, NaN, пока в окне есть NaN
+func (w *RollingWindow[T]) Max() (T, bool) {
+	if w.sum.nans > 0 {
+		return nan[T](), true
+	}
+
+	entry, ok := w.maximums.Front()
+	return entry.value, ok
+}
+
+// nan - NaN в типе T, вызывается только для чисел с плавающей точкой
+func nan[T Number]() T {
+	value := math.NaN()
+	return T(value)
+}
+
+// Snapshot - значения окна от самого старого к самому новому
+func (w *RollingWindow[T]) Snapshot() []T {
+	return w.values.Snapshot()
+}
+
+func TestRollingWindow(t *testing.T) {
+	window := NewRollingWindow[int](3)
+
+	_, ok := window.Min()
+	assert.False(t, ok)
+	assert.True(t, math.IsNaN(window.Mean()))
+
+	tests := []struct {
+		value    int
+		snapshot []int
+		sum      int
+		min, max int
+	}{
+		{value: 5, snapshot: []int{5}, sum: 5, min: 5, max: 5},
+		{value: 1, snapshot: []int{5, 1}, sum: 6, min: 1, max: 5},
+		{value: 3, snapshot: []int{5, 1, 3}, sum: 9, min: 1, max: 5},
+		{value: 4, snapshot: []int{1, 3, 4}, sum: 8, min: 1, max: 4},
+		{value: 2, snapshot: []int{3, 4, 2}, sum: 9, min: 2, max: 4},
+		{value: 2, snapshot: []int{4, 2, 2}, sum: 8, min: 2, max: 4},
+		{value: -7, snapshot: []int{2, 2, -7}, sum: -3, min: -7, max: 2},
+	}
+
+	for _, test := range tests {
+		window.Push(test.value)
+		assert.Equal(t, test.snapshot, window.Snapshot())
+		assert.Equal(t, float64(test.sum), window.Sum())
+
+		minimum, _ := window.Min()
+		maximum, _ := window.Max()
+		assert.Equal(t, test.min, minimum)
+		assert.Equal(t, test.max, maximum)
+	}
+
+	assert.InDelta(t, -1.0, window.Mean(), 1e-9)
+}
+
+func TestRollingWindowRandom(t *testing.T) {
+	const size = 16
+	window := NewRollingWindow[float64](size)
+
+	for i := 0; i < 10000; i++ {
+		window.Push(math.Round(rand.NormFloat64() * 100))
+
+		snapshot := window.Snapshot()
+		assert.LessOrEqual(t, len(snapshot), size)
+
+		minimum, _ := window.Min()
+		maximum, _ := window.Max()
+		assert.Equal(t, slices.Min(snapshot), minimum)
+		assert.Equal(t, slices.Max(snapshot), maximum)
+
+		var sum float64
+		for _, value := range snapshot {
+			sum += value
+		}
+		assert.InDelta(t, sum, window.Sum(), 1e-6)
+	}
+}
+
+func TestRollingWindowSmallIntegers(t *testing.T) {
+	signed := NewRollingWindow[int8](3)
+	for _, value := range []int8{100, 100, 100, -128, -128} {
+		signed.Push(value)
+	}
+	assert.Equal(t, -156.0, signed.Sum())
+	assert.InDelta(t, -52.0, signed.Mean(), 1e-9)
+
+	unsigned := NewRollingWindow[uint16](3)
+	for _, value := range []uint16{60000, 60000, 60000, 1} {
+		unsigned.Push(value)
+	}
+	assert.Equal(t, 120001.0, unsigned.Sum())
+	assert.InDelta(t, 40000.333, unsigned.Mean(), 1e-3)
+}
+
+func TestRollingWindowNonFinite(t *testing.T) {
+	window := NewRollingWindow[float64](2)
+
+	window.Push(math.Inf(1))
+	window.Push(1)
+	assert.True(t, math.IsInf(window.Sum(), 1))
+
+	window.Push(math.Inf(-1))
+	assert.True(t, math.IsInf(window.Sum(), -1))
+
+	// после вытеснения бесконечностей сумма снова конечна
+	window.Push(2)
+	window.Push(3)
+	assert.Equal(t, 5.0, window.Sum())
+
+	window.Push(math.NaN())
+	assert.True(t, math.IsNaN(window.Sum()))
+	window.Push(4)
+	window.Push(5)
+	assert.Equal(t, 9.0, window.Sum())
+	assert.Equal(t, 4.5, window.Mean())
+}
+
+func TestRollingWindowNaNMinMax(t *testing.T) {
+	window := NewRollingWindow[float32](3)
+
+	window.Push(5)
+	window.Push(float32(math.NaN()))
+	window.Push(3)
+
+	minimum, ok := window.Min()
+	assert.True(t, ok)
+	assert.True(t, math.IsNaN(float64(minimum)))
+	maximum, _ := window.Max()
+	assert.True(t, math.IsNaN(float64(maximum)))
+
+	// NaN покинул окно, агрегаты считаются по оставшимся значениям
+	window.Push(4)
+	window.Push(1)
+	minimum, _ = window.Min()
+	assert.Equal(t, float32(1), minimum)
+	maximum, _ = window.Max()
+	assert.Equal(t, float32(4), maximum)
+}
+
+func TestRollingWindowDrift(t *testing.T) {
+	window := NewRollingWindow[float64](2)
+
+	// без компенсации 0.1 теряется на фоне 1e16 и после вытеснения сумма неверна
+	window.Push(1e16)
+	window.Push(0.1)
+	window.Push(0.2)
+	assert.InDelta(t, 0.3, window.Sum(), 1e-12)
+
+	for i := 0; i < 100000; i++ {
+		window.Push(rand.Float64() * 1e6)
+	}
+	snapshot := window.Snapshot()
+	assert.InDelta(t, snapshot[0]+snapshot[1], window.Sum(), 1e-6)
+}
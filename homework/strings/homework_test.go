@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"hash/maphash"
 	"reflect"
 	"testing"
 	"unsafe"
@@ -8,34 +10,52 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+var ErrStringViewMutated = errors.New("memory under string view was mutated")
+
+// cowState - общее состояние для всех копий буфера, разделяющих одни данные
+type cowState struct {
+	refs     int
+	viewed   bool   // на данные указывает хотя бы одна выданная строка
+	viewHash uint64 // хэш данных на момент выдачи первой строки (только в строгом режиме)
+}
+
 type COWBuffer struct {
-	data []byte
-	refs *int
+	data   []byte
+	state  *cowState
+	strict bool
 }
 
+var viewSeed = maphash.MakeSeed()
+
 // NewCOWBuffer - создать буффер с определенными данными
 func NewCOWBuffer(data []byte) COWBuffer {
-	r := 1
-
 	//d := make([]byte, len(data))
 	//copy(d, data)
 
 	return COWBuffer{
-		data: data,
-		refs: &r,
+		data:  data,
+		state: &cowState{refs: 1},
 	}
 }
 
+// NewStrictCOWBuffer - создать буффер, который проверяет, что память
+// под выданными строками не была изменена в обход буфера
+func NewStrictCOWBuffer(data []byte) COWBuffer {
+	buffer := NewCOWBuffer(data)
+	buffer.strict = true
+	return buffer
+}
+
 // Clone - создать новую копию буфера
 func (b *COWBuffer) Clone() COWBuffer {
-	*b.refs++
+	b.state.refs++
 	return *b
 }
 
 // Close - перестать использовать копию буффера
 func (b *COWBuffer) Close() {
-	if *b.refs > 0 {
-		*b.refs--
+	if b.state.refs > 0 {
+		b.state.refs--
 	}
 }
 
@@ -44,24 +64,48 @@ func (b *COWBuffer) Update(index int, value byte) bool {
 	if index < 0 || index >= len(b.data) {
 		return false
 	}
-	if *b.refs > 1 {
-		*b.refs--
-
-		newData := NewCOWBuffer(append([]byte(nil), b.data...))
-
-		b.data = newData.data
-		b.refs = newData.refs
+	// строки, выданные ранее, указывают на те же данные, поэтому
+	// менять их на месте нельзя даже при единственной ссылке
+	if b.state.refs > 1 || b.state.viewed {
+		// проверка стоит O(n), как и само копирование, поэтому выполняется
+		// только здесь, а не при каждом изменении
+		if err := b.Verify(); err != nil {
+			panic(err)
+		}
+
+		b.state.refs--
+
+		// у копии еще нет выданных строк, изменения снова выполняются на месте
+		b.data = append([]byte(nil), b.data...)
+		b.state = &cowState{refs: 1}
 	}
 
 	b.data[index] = value
 	return true
 }
 
-// String - сконвертировать буффер в строку
+// String - сконвертировать буффер в строку без копирования
+// (после этого данные считаются неизменяемыми, и Update будет копировать их)
 func (b *COWBuffer) String() string {
+	if b.strict && !b.state.viewed {
+		b.state.viewHash = maphash.Bytes(viewSeed, b.data)
+	}
+	b.state.viewed = true
+
 	return unsafe.String(unsafe.SliceData(b.data), len(b.data))
 }
 
+// Verify - проверить, что память под выданными строками не изменилась (только в строгом режиме)
+func (b *COWBuffer) Verify() error {
+	if !b.strict || !b.state.viewed {
+		return nil
+	}
+	if maphash.Bytes(viewSeed, b.data) != b.state.viewHash {
+		return ErrStringViewMutated
+	}
+	return nil
+}
+
 func TestCOWBuffer(t *testing.T) {
 	data := []byte{'a', 'b', 'c', 'd'}
 	buffer := NewCOWBuffer(data)
@@ -91,12 +135,70 @@ func TestCOWBuffer(t *testing.T) {
 
 	copy1.Close()
 
+	view := copy2.String()
 	previous := copy2.data
 	copy2.Update(0, 'f')
 	current := copy2.data
 
-	// 1 reference - don't need to copy buffer during update
-	assert.Equal(t, unsafe.SliceData(previous), unsafe.SliceData(current))
+	// 1 reference, but string view still points to the data - need to copy buffer during update
+	assert.NotEqual(t, unsafe.SliceData(previous), unsafe.SliceData(current))
+	assert.Equal(t, "abcd", view)
 
 	copy2.Close()
 }
+
+func TestCOWBufferWithoutViews(t *testing.T) {
+	data := []byte{'a', 'b', 'c', 'd'}
+	buffer := NewCOWBuffer(data)
+	defer buffer.Close()
+
+	previous := buffer.data
+	assert.True(t, buffer.Update(0, 'f'))
+	current := buffer.data
+
+	// 1 reference and no string views - don't need to copy buffer during update
+	assert.Equal(t, unsafe.SliceData(previous), unsafe.SliceData(current))
+	assert.Equal(t, "fbcd", buffer.String())
+}
+
+func TestStrictCOWBuffer(t *testing.T) {
+	data := []byte{'a', 'b', 'c', 'd'}
+	buffer := NewStrictCOWBuffer(data)
+	defer buffer.Close()
+
+	view := buffer.String()
+	assert.NoError(t, buffer.Verify())
+
+	assert.True(t, buffer.Update(0, 'g'))
+	assert.NoError(t, buffer.Verify())
+	assert.Equal(t, "abcd", view)
+
+	strict := NewStrictCOWBuffer(data)
+	_ = strict.String()
+	data[1] = 'x' // mutation in bypass of the buffer
+
+	assert.ErrorIs(t, strict.Verify(), ErrStringViewMutated)
+	assert.Panics(t, func() { strict.Update(0, 'g') })
+}
+
+func TestCOWBufferViewResetAfterCopy(t *testing.T) {
+	buffer := NewStrictCOWBuffer([]byte{'a', 'b', 'c', 'd'})
+	defer buffer.Close()
+
+	view := buffer.String()
+	assert.True(t, buffer.Update(0, 'x'))
+	assert.False(t, buffer.state.viewed)
+
+	// после копирования новых строк не выдавалось - данные меняются на месте
+	copied := buffer.data
+	assert.True(t, buffer.Update(1, 'y'))
+	assert.Equal(t, unsafe.SliceData(copied), unsafe.SliceData(buffer.data))
+	assert.Equal(t, "xycd", buffer.String())
+	assert.Equal(t, "abcd", view)
+
+	// без выданных строк проверка не выполняется и данные не хэшируются
+	unviewed := NewStrictCOWBuffer([]byte{'a', 'b'})
+	unviewed.data[0] = 'z'
+	assert.True(t, unviewed.Update(1, 'y'))
+	assert.Zero(t, unviewed.state.viewHash)
+}
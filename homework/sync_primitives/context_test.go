@@ -0,0 +1,271 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -race -v homework_test.go stress_test.go context_test.go
+
+// Семафор с весами и AcquireContext - в lessons/sync_primitives/semaphore
+
+// waiter - ожидающий захвата: ready закрывается, когда захват выполнен за него
+type waiter struct {
+	write bool // писатель или читатель
+	ready chan struct{}
+}
+
+// ChanRWMutex - мьютекс чтения-записи, ожидание которого можно прервать через
+// контекст: ожидающие стоят в FIFO-очереди, и каждый ждет закрытия своего канала,
+// поэтому отмененный просто удаляется из очереди; читатели за ожидающим
+// писателем встают в очередь, что дает писателям приоритет
+type ChanRWMutex struct {
+	mutex   sync.Mutex
+	readers int
+	writer  bool
+	waiters list.List // *waiter в порядке прихода
+}
+
+func (m *ChanRWMutex) Lock() {
+	_ = m.LockContext(context.Background())
+}
+
+func (m *ChanRWMutex) RLock() {
+	_ = m.RLockContext(context.Background())
+}
+
+// LockContext - захватить мьютекс на запись, ctx.Err(), если ожидание прервано
+func (m *ChanRWMutex) LockContext(ctx context.Context) error {
+	return m.acquire(ctx, true)
+}
+
+// RLockContext - захватить мьютекс на чтение, ctx.Err(), если ожидание прервано
+func (m *ChanRWMutex) RLockContext(ctx context.Context) error {
+	return m.acquire(ctx, false)
+}
+
+func (m *ChanRWMutex) acquire(ctx context.Context, write bool) error {
+	m.mutex.Lock()
+	if m.waiters.Len() == 0 && m.available(write) {
+		m.take(write)
+		m.mutex.Unlock()
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		m.mutex.Unlock()
+		return err
+	}
+
+	w := &waiter{write: write, ready: make(chan struct{})}
+	element := m.waiters.PushBack(w)
+	m.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		// захват выполнен одновременно с отменой, возвращаем его обратно
+		m.release(write)
+	default:
+		m.waiters.Remove(element)
+		m.wakeWaiters() // за ушедшим писателем могли стоять читатели
+	}
+
+	return ctx.Err()
+}
+
+func (m *ChanRWMutex) Unlock() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.writer {
+		panic("rwmutex: Unlock of unlocked ChanRWMutex")
+	}
+
+	m.release(true)
+}
+
+func (m *ChanRWMutex) RUnlock() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.readers == 0 {
+		panic("rwmutex: RUnlock of unlocked ChanRWMutex")
+	}
+
+	m.release(false)
+}
+
+func (m *ChanRWMutex) available(write bool) bool {
+	if write {
+		return !m.writer && m.readers == 0
+	}
+
+	return !m.writer
+}
+
+func (m *ChanRWMutex) take(write bool) {
+	if write {
+		m.writer = true
+	} else {
+		m.readers++
+	}
+}
+
+func (m *ChanRWMutex) release(write bool) {
+	if write {
+		m.writer = false
+	} else {
+		m.readers--
+	}
+
+	m.wakeWaiters()
+}
+
+// wakeWaiters - выдать захват ожидающим из начала очереди: одному писателю
+// или всем читателям до первого писателя
+func (m *ChanRWMutex) wakeWaiters() {
+	for element := m.waiters.Front(); element != nil; element = m.waiters.Front() {
+		w := element.Value.(*waiter)
+		if !m.available(w.write) {
+			return
+		}
+
+		m.take(w.write)
+		m.waiters.Remove(element)
+		close(w.ready)
+	}
+}
+
+func TestChanRWMutexLockContext(t *testing.T) {
+	var mutex ChanRWMutex
+	mutex.RLock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, mutex.LockContext(ctx), context.DeadlineExceeded)
+	assert.Zero(t, mutex.waiters.Len()) // отмененный писатель не остался в очереди
+
+	// после ухода писателя читатели снова проходят сразу
+	assert.NoError(t, mutex.RLockContext(context.Background()))
+	mutex.RUnlock()
+	mutex.RUnlock()
+
+	mutex.Lock()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, mutex.RLockContext(canceled), context.Canceled)
+	assert.ErrorIs(t, mutex.LockContext(canceled), context.Canceled)
+	mutex.Unlock()
+
+	// захват без ожидания выполняется даже с отмененным контекстом
+	assert.NoError(t, mutex.LockContext(canceled))
+	mutex.Unlock()
+}
+
+func TestChanRWMutexWriterPriority(t *testing.T) {
+	var mutex ChanRWMutex
+	mutex.RLock()
+
+	writerCtx, cancelWriter := context.WithCancel(context.Background())
+	writerDone := make(chan error)
+	go func() {
+		writerDone <- mutex.LockContext(writerCtx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		mutex.mutex.Lock()
+		defer mutex.mutex.Unlock()
+		return mutex.waiters.Len() == 1
+	}, time.Second, time.Millisecond)
+
+	// читатель встает в очередь за писателем
+	readerDone := make(chan error)
+	go func() {
+		readerDone <- mutex.RLockContext(context.Background())
+	}()
+
+	select {
+	case <-readerDone:
+		t.Fatal("reader overtook waiting writer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// отмена писателя пропускает читателя, стоявшего за ним
+	cancelWriter()
+	assert.ErrorIs(t, <-writerDone, context.Canceled)
+	assert.NoError(t, <-readerDone)
+
+	mutex.RUnlock()
+	mutex.RUnlock()
+	assert.Zero(t, mutex.waiters.Len())
+}
+
+func TestChanRWMutexUnlockOfUnlocked(t *testing.T) {
+	var mutex ChanRWMutex
+	assert.PanicsWithValue(t, "rwmutex: Unlock of unlocked ChanRWMutex", mutex.Unlock)
+	assert.PanicsWithValue(t, "rwmutex: RUnlock of unlocked ChanRWMutex", mutex.RUnlock)
+}
+
+func TestChanRWMutexStress(t *testing.T) {
+	const goroutines = 16
+	const iterations = 500
+
+	var mutex ChanRWMutex
+	var readers, writers atomic.Int32
+	var violations atomic.Int32
+	check := func() {
+		if writers.Load() > 1 || (writers.Load() == 1 && readers.Load() > 0) {
+			violations.Add(1)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				// часть ожиданий прерывается, пока мьютекс занят другими
+				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(rand.IntN(50))*time.Microsecond)
+				if rand.IntN(4) == 0 {
+					if mutex.LockContext(ctx) == nil {
+						writers.Add(1)
+						check()
+						writers.Add(-1)
+						mutex.Unlock()
+					}
+				} else if mutex.RLockContext(ctx) == nil {
+					readers.Add(1)
+					check()
+					readers.Add(-1)
+					mutex.RUnlock()
+				}
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Zero(t, violations.Load())
+
+	// все захваты возвращены, в очереди никого не осталось
+	assert.Zero(t, mutex.waiters.Len())
+	assert.Zero(t, mutex.readers)
+	assert.False(t, mutex.writer)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -8,25 +9,129 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// go test -v homework_test.go stress_test.go context_test.go
+
+// RWMutex - мьютекс чтения-записи с приоритетом писателей: пока писатель ждет,
+// новые читатели не захватывают мьютекс, поэтому поток читателей не блокирует
+// писателя навсегда; нулевое значение готово к использованию
 type RWMutex struct {
-	// need to implement
+	mutex   sync.Mutex
+	readers sync.Cond // ждут читатели, будятся все сразу, когда писателей не осталось
+	writers sync.Cond // ждут писатели, будится по одному, чтобы не было "стада"
+
+	activeReaders  int  // количество активных читателей
+	writer         bool // захвачен ли мьютекс писателем
+	waitingWriters int  // количество писателей, ожидающих захвата
+}
+
+// lock - захватить внутренний мьютекс, лениво инициализируя условные переменные
+func (m *RWMutex) lock() {
+	m.mutex.Lock()
+	if m.readers.L == nil {
+		m.readers.L = &m.mutex
+		m.writers.L = &m.mutex
+	}
+}
+
+// wakeUp - мьютекс освободился: при ожидающем писателе будится только он один
+// (читатели все равно пропустят его вперед), иначе все ожидающие читатели
+func (m *RWMutex) wakeUp() {
+	if m.waitingWriters > 0 {
+		m.writers.Signal()
+	} else {
+		m.readers.Broadcast()
+	}
 }
 
 func (m *RWMutex) Lock() {
-	// need to implement
+	m.lock()
+	defer m.mutex.Unlock()
+
+	m.waitingWriters++
+	for m.writer || m.activeReaders > 0 {
+		m.writers.Wait()
+	}
+
+	m.waitingWriters--
+	m.writer = true
+}
+
+// TryLock - захватить мьютекс на запись без ожидания
+func (m *RWMutex) TryLock() bool {
+	m.lock()
+	defer m.mutex.Unlock()
+
+	if m.writer || m.activeReaders > 0 {
+		return false
+	}
+
+	m.writer = true
+	return true
 }
 
 func (m *RWMutex) Unlock() {
-	// need to implement
+	m.lock()
+	defer m.mutex.Unlock()
+
+	if !m.writer {
+		panic("rwmutex: Unlock of unlocked RWMutex")
+	}
+
+	m.writer = false
+	m.wakeUp()
 }
 
 func (m *RWMutex) RLock() {
-	// need to implement
+	m.lock()
+	defer m.mutex.Unlock()
+
+	for m.writer || m.waitingWriters > 0 {
+		m.readers.Wait()
+	}
+
+	m.activeReaders++
+}
+
+// TryRLock - захватить мьютекс на чтение без ожидания
+func (m *RWMutex) TryRLock() bool {
+	m.lock()
+	defer m.mutex.Unlock()
+
+	if m.writer || m.waitingWriters > 0 {
+		return false
+	}
 
+	m.activeReaders++
+	return true
 }
 
 func (m *RWMutex) RUnlock() {
-	// need to implement
+	m.lock()
+	defer m.mutex.Unlock()
+
+	if m.activeReaders == 0 {
+		panic("rwmutex: RUnlock of unlocked RWMutex")
+	}
+
+	m.activeReaders--
+	if m.activeReaders == 0 {
+		m.wakeUp()
+	}
+}
+
+// RLocker - sync.Locker, который захватывает мьютекс на чтение
+func (m *RWMutex) RLocker() sync.Locker {
+	return (*rlocker)(m)
+}
+
+type rlocker RWMutex
+
+func (r *rlocker) Lock() {
+	(*RWMutex)(r).RLock()
+}
+
+func (r *rlocker) Unlock() {
+	(*RWMutex)(r).RUnlock()
 }
 
 func TestRWMutexWithWriter(t *testing.T) {
@@ -121,3 +226,71 @@ func TestRWMutexWithWriterPriority(t *testing.T) {
 	assert.True(t, mutualExlusionWithWriter.Load())
 	assert.Equal(t, int32(1), readersCount.Load())
 }
+
+func TestRWMutexTryLock(t *testing.T) {
+	var mutex RWMutex
+
+	assert.True(t, mutex.TryRLock())
+	assert.True(t, mutex.TryRLock())
+	assert.False(t, mutex.TryLock())
+
+	mutex.RUnlock()
+	mutex.RUnlock()
+	assert.True(t, mutex.TryLock())
+	assert.False(t, mutex.TryLock())
+	assert.False(t, mutex.TryRLock())
+
+	mutex.Unlock()
+	assert.True(t, mutex.TryRLock())
+
+	// при ожидающем писателе новые читатели не допускаются
+	locked := make(chan struct{})
+	go func() {
+		mutex.Lock()
+		close(locked)
+	}()
+
+	assert.Eventually(t, func() bool {
+		if mutex.TryRLock() {
+			mutex.RUnlock() // писатель еще не встал в очередь
+			return false
+		}
+		return true
+	}, time.Second, time.Millisecond)
+
+	mutex.RUnlock()
+	<-locked
+	mutex.Unlock()
+}
+
+func TestRWMutexRLocker(t *testing.T) {
+	var mutex RWMutex
+	locker := mutex.RLocker()
+
+	locker.Lock()
+	assert.True(t, mutex.TryRLock())
+	assert.False(t, mutex.TryLock())
+
+	mutex.RUnlock()
+	locker.Unlock()
+	assert.True(t, mutex.TryLock())
+	mutex.Unlock()
+}
+
+func TestRWMutexUnlockOfUnlocked(t *testing.T) {
+	var mutex RWMutex
+	assert.PanicsWithValue(t, "rwmutex: Unlock of unlocked RWMutex", mutex.Unlock)
+	assert.PanicsWithValue(t, "rwmutex: RUnlock of unlocked RWMutex", mutex.RUnlock)
+
+	mutex.RLock()
+	assert.Panics(t, mutex.Unlock)
+	mutex.RUnlock()
+
+	mutex.Lock()
+	assert.Panics(t, mutex.RUnlock)
+	mutex.Unlock()
+
+	// после паники мьютекс остается пригодным к использованию
+	assert.True(t, mutex.TryLock())
+	mutex.Unlock()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -race -v homework_test.go stress_test.go context_test.go
+// go test -bench=. homework_test.go stress_test.go context_test.go
+
+// rwLocker - общий интерфейс RWMutex и sync.RWMutex, чтобы гонять их на одних тестах
+type rwLocker interface {
+	sync.Locker
+	RLock()
+	RUnlock()
+	TryLock() bool
+	TryRLock() bool
+	RLocker() sync.Locker
+}
+
+var implementations = map[string]func() rwLocker{
+	"RWMutex":      func() rwLocker { return &RWMutex{} },
+	"sync.RWMutex": func() rwLocker { return &sync.RWMutex{} },
+}
+
+func TestRWMutexStress(t *testing.T) {
+	const goroutines = 16
+	const iterations = 2000
+
+	for name, create := range implementations {
+		t.Run(name, func(t *testing.T) {
+			mutex := create()
+
+			var readers, writers atomic.Int32
+			var violations atomic.Int32
+			check := func() {
+				if writers.Load() > 1 || (writers.Load() == 1 && readers.Load() > 0) {
+					violations.Add(1)
+				}
+			}
+
+			var wg sync.WaitGroup
+			wg.Add(goroutines)
+			for g := 0; g < goroutines; g++ {
+				go func(id int) {
+					defer wg.Done()
+					for i := 0; i < iterations; i++ {
+						switch {
+						case (id+i)%8 == 0:
+							mutex.Lock()
+							writers.Add(1)
+							check()
+							writers.Add(-1)
+							mutex.Unlock()
+						case (id+i)%8 == 1 && mutex.TryLock():
+							writers.Add(1)
+							check()
+							writers.Add(-1)
+							mutex.Unlock()
+						case (id+i)%8 == 2 && mutex.TryRLock():
+							readers.Add(1)
+							check()
+							readers.Add(-1)
+							mutex.RUnlock()
+						default:
+							locker := mutex.RLocker()
+							locker.Lock()
+							readers.Add(1)
+							check()
+							readers.Add(-1)
+							locker.Unlock()
+						}
+					}
+				}(g)
+			}
+
+			wg.Wait()
+			assert.Zero(t, violations.Load())
+		})
+	}
+}
+
+// TestRWMutexWriterFairness - читатели, пришедшие после ожидающего писателя,
+// получают мьютекс только после него; проверяется порядок, а не время ожидания
+func TestRWMutexWriterFairness(t *testing.T) {
+	const rounds = 20
+	const readers = 8
+
+	for name, create := range implementations {
+		t.Run(name, func(t *testing.T) {
+			mutex := create()
+
+			for round := 0; round < rounds; round++ {
+				var orderMutex sync.Mutex
+				var order []string
+				record := func(who string) {
+					orderMutex.Lock()
+					order = append(order, who)
+					orderMutex.Unlock()
+				}
+
+				mutex.RLock()
+
+				var wg sync.WaitGroup
+				wg.Add(1 + readers)
+				go func() {
+					defer wg.Done()
+					mutex.Lock()
+					record("writer")
+					mutex.Unlock()
+				}()
+
+				// писатель встал в очередь: новые читатели больше не проходят
+				assert.Eventually(t, func() bool {
+					if mutex.TryRLock() {
+						mutex.RUnlock()
+						return false
+					}
+					return true
+				}, 5*time.Second, time.Millisecond)
+
+				for r := 0; r < readers; r++ {
+					go func() {
+						defer wg.Done()
+						mutex.RLock()
+						record("reader")
+						mutex.RUnlock()
+					}()
+				}
+
+				mutex.RUnlock()
+				wg.Wait()
+
+				assert.Len(t, order, 1+readers)
+				assert.Equal(t, "writer", order[0])
+			}
+		})
+	}
+}
+
+func BenchmarkRWMutex(b *testing.B) {
+	for name, create := range implementations {
+		b.Run(name+" read heavy", func(b *testing.B) {
+			mutex := create()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if i%100 == 0 {
+						mutex.Lock()
+						mutex.Unlock()
+					} else {
+						mutex.RLock()
+						mutex.RUnlock()
+					}
+				}
+			})
+		})
+	}
+}
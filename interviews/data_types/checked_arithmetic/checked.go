@@ -0,0 +1,190 @@
+package checked
+
+import (
+	"errors"
+	"unsafe"
+)
+
+var (
+	ErrIntOverflow    = errors.New("integer overflow")
+	ErrDivisionByZero = errors.New("integer division by zero")
+)
+
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+type Integer interface {
+	Signed | Unsigned
+}
+
+func isSigned[T Integer]() bool {
+	var zero T
+	return ^zero < 0
+}
+
+func MaxValue[T Integer]() T {
+	if isSigned[T]() {
+		bits := unsafe.Sizeof(T(0)) * 8
+		return T(1)<<(bits-1) - 1
+	}
+
+	var zero T
+	return ^zero
+}
+
+func MinValue[T Integer]() T {
+	if isSigned[T]() {
+		return -MaxValue[T]() - 1
+	}
+
+	return 0
+}
+
+func Add[T Integer](lhs, rhs T) (T, error) {
+	result := lhs + rhs
+	if (rhs > 0 && result < lhs) || (rhs < 0 && result > lhs) {
+		return 0, ErrIntOverflow
+	}
+
+	return result, nil
+}
+
+func Sub[T Integer](lhs, rhs T) (T, error) {
+	result := lhs - rhs
+	if (rhs > 0 && result > lhs) || (rhs < 0 && result < lhs) {
+		return 0, ErrIntOverflow
+	}
+
+	return result, nil
+}
+
+func Mul[T Integer](lhs, rhs T) (T, error) {
+	if lhs == 0 || rhs == 0 {
+		return 0, nil
+	}
+
+	// MinValue * -1 не ловится делением: MinValue / -1 == MinValue
+	if isSigned[T]() && ((lhs == MinValue[T]() && rhs == ^T(0)) || (rhs == MinValue[T]() && lhs == ^T(0))) {
+		return 0, ErrIntOverflow
+	}
+
+	result := lhs * rhs
+	if result/rhs != lhs {
+		return 0, ErrIntOverflow
+	}
+
+	return result, nil
+}
+
+func Div[T Integer](lhs, rhs T) (T, error) {
+	if rhs == 0 {
+		return 0, ErrDivisionByZero
+	}
+	if isSigned[T]() && lhs == MinValue[T]() && rhs == ^T(0) {
+		return 0, ErrIntOverflow
+	}
+
+	return lhs / rhs, nil
+}
+
+func Neg[T Integer](value T) (T, error) {
+	if isSigned[T]() {
+		if value == MinValue[T]() {
+			return 0, ErrIntOverflow
+		}
+	} else if value != 0 {
+		return 0, ErrIntOverflow
+	}
+
+	return -value, nil
+}
+
+// Convert - преобразовать значение к другому целочисленному типу без потери данных
+func Convert[To, From Integer](value From) (To, error) {
+	result := To(value)
+	if From(result) != value || (result < 0) != (value < 0) {
+		return 0, ErrIntOverflow
+	}
+
+	return result, nil
+}
+
+// Saturating варианты возвращают ближайшую границу типа вместо ошибки
+
+func AddSat[T Integer](lhs, rhs T) T {
+	result, err := Add(lhs, rhs)
+	if err == nil {
+		return result
+	}
+	if rhs > 0 {
+		return MaxValue[T]()
+	}
+
+	return MinValue[T]()
+}
+
+func SubSat[T Integer](lhs, rhs T) T {
+	result, err := Sub(lhs, rhs)
+	if err == nil {
+		return result
+	}
+	if rhs > 0 {
+		return MinValue[T]()
+	}
+
+	return MaxValue[T]()
+}
+
+func MulSat[T Integer](lhs, rhs T) T {
+	result, err := Mul(lhs, rhs)
+	if err == nil {
+		return result
+	}
+	if (lhs < 0) != (rhs < 0) {
+		return MinValue[T]()
+	}
+
+	return MaxValue[T]()
+}
+
+// DivSat - как и обычное деление, паникует при делении на ноль
+func DivSat[T Integer](lhs, rhs T) T {
+	result, err := Div(lhs, rhs)
+	if errors.Is(err, ErrIntOverflow) {
+		return MaxValue[T]()
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+func NegSat[T Integer](value T) T {
+	result, err := Neg(value)
+	if err == nil {
+		return result
+	}
+	if isSigned[T]() {
+		return MaxValue[T]()
+	}
+
+	return 0
+}
+
+func ConvertSat[To, From Integer](value From) To {
+	result, err := Convert[To](value)
+	if err == nil {
+		return result
+	}
+	if value < 0 {
+		return MinValue[To]()
+	}
+
+	return MaxValue[To]()
+}
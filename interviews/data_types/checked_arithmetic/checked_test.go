@@ -0,0 +1,163 @@
+package checked
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -fuzz=FuzzSigned -fuzztime=30s .
+
+func TestLimits(t *testing.T) {
+	assert.Equal(t, int8(math.MaxInt8), MaxValue[int8]())
+	assert.Equal(t, int8(math.MinInt8), MinValue[int8]())
+	assert.Equal(t, int64(math.MaxInt64), MaxValue[int64]())
+	assert.Equal(t, int64(math.MinInt64), MinValue[int64]())
+	assert.Equal(t, uint16(math.MaxUint16), MaxValue[uint16]())
+	assert.Equal(t, uint16(0), MinValue[uint16]())
+}
+
+func TestChecked(t *testing.T) {
+	_, err := Add[int8](math.MaxInt8, 1)
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = Sub[uint](0, 1)
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = Mul[int](math.MinInt, -1)
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = Div[int32](math.MinInt32, -1)
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = Div[int32](1, 0)
+	assert.ErrorIs(t, err, ErrDivisionByZero)
+	_, err = Neg[int16](math.MinInt16)
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = Convert[uint8](int(-1))
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = Convert[int64](uint64(math.MaxUint64))
+	assert.ErrorIs(t, err, ErrIntOverflow)
+
+	result, err := Mul[int64](-3, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-15), result)
+
+	converted, err := Convert[int8](int64(-128))
+	assert.NoError(t, err)
+	assert.Equal(t, int8(-128), converted)
+
+	assert.Equal(t, int8(math.MaxInt8), AddSat[int8](100, 100))
+	assert.Equal(t, int8(math.MinInt8), SubSat[int8](-100, 100))
+	assert.Equal(t, uint8(0), SubSat[uint8](1, 2))
+	assert.Equal(t, int32(math.MinInt32), MulSat[int32](math.MaxInt32, -2))
+	assert.Equal(t, int32(math.MaxInt32), DivSat[int32](math.MinInt32, -1))
+	assert.Equal(t, int32(math.MaxInt32), NegSat[int32](math.MinInt32))
+	assert.Equal(t, uint32(0), NegSat[uint32](1))
+	assert.Equal(t, uint8(0), ConvertSat[uint8](int16(-300)))
+	assert.Equal(t, int8(math.MaxInt8), ConvertSat[int8](uint64(300)))
+	assert.Panics(t, func() { DivSat[int](1, 0) })
+}
+
+// checkResult - сравнить результат с точным значением, посчитанным в math/big
+func checkResult[T Integer](t *testing.T, result T, err error, saturated T, exact *big.Int) {
+	t.Helper()
+
+	low, high := toBig(MinValue[T]()), toBig(MaxValue[T]())
+	switch {
+	case exact.Cmp(low) < 0:
+		assert.ErrorIs(t, err, ErrIntOverflow)
+		assert.Equal(t, MinValue[T](), saturated)
+	case exact.Cmp(high) > 0:
+		assert.ErrorIs(t, err, ErrIntOverflow)
+		assert.Equal(t, MaxValue[T](), saturated)
+	default:
+		assert.NoError(t, err)
+		assert.Equal(t, exact.String(), toBig(result).String())
+		assert.Equal(t, result, saturated)
+	}
+}
+
+func toBig[T Integer](value T) *big.Int {
+	if isSigned[T]() {
+		return big.NewInt(int64(value))
+	}
+
+	return new(big.Int).SetUint64(uint64(value))
+}
+
+func fuzzBinary[T Integer](t *testing.T, lhs, rhs T) {
+	result, err := Add(lhs, rhs)
+	checkResult(t, result, err, AddSat(lhs, rhs), new(big.Int).Add(toBig(lhs), toBig(rhs)))
+
+	result, err = Sub(lhs, rhs)
+	checkResult(t, result, err, SubSat(lhs, rhs), new(big.Int).Sub(toBig(lhs), toBig(rhs)))
+
+	result, err = Mul(lhs, rhs)
+	checkResult(t, result, err, MulSat(lhs, rhs), new(big.Int).Mul(toBig(lhs), toBig(rhs)))
+
+	result, err = Neg(lhs)
+	checkResult(t, result, err, NegSat(lhs), new(big.Int).Neg(toBig(lhs)))
+
+	if rhs == 0 {
+		_, err = Div(lhs, rhs)
+		assert.ErrorIs(t, err, ErrDivisionByZero)
+		return
+	}
+
+	result, err = Div(lhs, rhs)
+	checkResult(t, result, err, DivSat(lhs, rhs), new(big.Int).Quo(toBig(lhs), toBig(rhs)))
+}
+
+func fuzzConvert[To, From Integer](t *testing.T, value From) {
+	result, err := Convert[To](value)
+	checkResult(t, result, err, ConvertSat[To](value), toBig(value))
+}
+
+func FuzzSigned(f *testing.F) {
+	f.Add(int64(0), int64(0))
+	f.Add(int64(math.MinInt64), int64(-1))
+	f.Add(int64(math.MaxInt64), int64(1))
+	f.Add(int64(math.MinInt32), int64(-1))
+	f.Add(int64(-128), int64(-1))
+
+	f.Fuzz(func(t *testing.T, lhs, rhs int64) {
+		fuzzBinary(t, lhs, rhs)
+		fuzzBinary(t, int(lhs), int(rhs))
+		fuzzBinary(t, int32(lhs), int32(rhs))
+		fuzzBinary(t, int16(lhs), int16(rhs))
+		fuzzBinary(t, int8(lhs), int8(rhs))
+	})
+}
+
+func FuzzUnsigned(f *testing.F) {
+	f.Add(uint64(0), uint64(0))
+	f.Add(uint64(math.MaxUint64), uint64(1))
+	f.Add(uint64(1), uint64(2))
+	f.Add(uint64(math.MaxUint32), uint64(math.MaxUint32))
+
+	f.Fuzz(func(t *testing.T, lhs, rhs uint64) {
+		fuzzBinary(t, lhs, rhs)
+		fuzzBinary(t, uint(lhs), uint(rhs))
+		fuzzBinary(t, uint32(lhs), uint32(rhs))
+		fuzzBinary(t, uint16(lhs), uint16(rhs))
+		fuzzBinary(t, uint8(lhs), uint8(rhs))
+	})
+}
+
+func FuzzConvert(f *testing.F) {
+	f.Add(int64(0))
+	f.Add(int64(-1))
+	f.Add(int64(math.MaxInt64))
+	f.Add(int64(math.MinInt64))
+	f.Add(int64(256))
+
+	f.Fuzz(func(t *testing.T, value int64) {
+		fuzzConvert[int8](t, value)
+		fuzzConvert[int16](t, value)
+		fuzzConvert[int32](t, value)
+		fuzzConvert[uint8](t, value)
+		fuzzConvert[uint32](t, value)
+		fuzzConvert[uint64](t, value)
+		fuzzConvert[int64](t, uint64(value))
+		fuzzConvert[uint16](t, int8(value))
+	})
+}
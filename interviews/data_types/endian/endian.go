@@ -0,0 +1,176 @@
+// Package endian - порядок байт для любых числовых типов: запись и чтение
+// в заданном порядке, определение порядка платформы и разворот срезов на месте
+package endian
+
+import (
+	"math/bits"
+	"unsafe"
+)
+
+type Number interface {
+	~int8 | ~int16 | ~int32 | ~int64 | ~int |
+		~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uint | ~uintptr |
+		~float32 | ~float64
+}
+
+type ByteOrder int
+
+const (
+	LittleEndian ByteOrder = iota
+	BigEndian
+)
+
+// HostOrder - порядок байт текущей платформы
+var HostOrder = detectHostOrder()
+
+func detectHostOrder() ByteOrder {
+	var number int16 = 0x0001
+	pointer := (*int8)(unsafe.Pointer(&number))
+	if *pointer == 1 {
+		return LittleEndian
+	}
+
+	return BigEndian
+}
+
+func (o ByteOrder) String() string {
+	if o == BigEndian {
+		return "BigEndian"
+	}
+
+	return "LittleEndian"
+}
+
+func (o ByteOrder) PutUint16(b []byte, v uint16) {
+	_ = b[1] // bounds check hint to compiler
+	if o == BigEndian {
+		b[0], b[1] = byte(v>>8), byte(v)
+		return
+	}
+
+	b[0], b[1] = byte(v), byte(v>>8)
+}
+
+func (o ByteOrder) Uint16(b []byte) uint16 {
+	_ = b[1]
+	if o == BigEndian {
+		return uint16(b[1]) | uint16(b[0])<<8
+	}
+
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+func (o ByteOrder) PutUint32(b []byte, v uint32) {
+	_ = b[3]
+	for i := 0; i < 4; i++ {
+		b[o.index(i, 4)] = byte(v >> (8 * i))
+	}
+}
+
+func (o ByteOrder) Uint32(b []byte) uint32 {
+	_ = b[3]
+	var v uint32
+	for i := 0; i < 4; i++ {
+		v |= uint32(b[o.index(i, 4)]) << (8 * i)
+	}
+
+	return v
+}
+
+func (o ByteOrder) PutUint64(b []byte, v uint64) {
+	_ = b[7]
+	for i := 0; i < 8; i++ {
+		b[o.index(i, 8)] = byte(v >> (8 * i))
+	}
+}
+
+func (o ByteOrder) Uint64(b []byte) uint64 {
+	_ = b[7]
+	var v uint64
+	for i := 0; i < 8; i++ {
+		v |= uint64(b[o.index(i, 8)]) << (8 * i)
+	}
+
+	return v
+}
+
+// index - позиция i-го младшего байта в представлении размером size
+func (o ByteOrder) index(i, size int) int {
+	if o == BigEndian {
+		return size - 1 - i
+	}
+
+	return i
+}
+
+// Swap - развернуть порядок байт любого числового значения
+func Swap[T Number](value T) T {
+	pointer := unsafe.Pointer(&value)
+	switch unsafe.Sizeof(value) {
+	case 2:
+		*(*uint16)(pointer) = bits.ReverseBytes16(*(*uint16)(pointer))
+	case 4:
+		*(*uint32)(pointer) = bits.ReverseBytes32(*(*uint32)(pointer))
+	case 8:
+		*(*uint64)(pointer) = bits.ReverseBytes64(*(*uint64)(pointer))
+	}
+
+	return value
+}
+
+// Put - записать значение в b в заданном порядке байт
+func Put[T Number](order ByteOrder, b []byte, value T) {
+	pointer := unsafe.Pointer(&value)
+	switch unsafe.Sizeof(value) {
+	case 1:
+		b[0] = *(*uint8)(pointer)
+	case 2:
+		order.PutUint16(b, *(*uint16)(pointer))
+	case 4:
+		order.PutUint32(b, *(*uint32)(pointer))
+	case 8:
+		order.PutUint64(b, *(*uint64)(pointer))
+	}
+}
+
+// Get - прочитать значение из b в заданном порядке байт
+func Get[T Number](order ByteOrder, b []byte) T {
+	var value T
+	pointer := unsafe.Pointer(&value)
+	switch unsafe.Sizeof(value) {
+	case 1:
+		*(*uint8)(pointer) = b[0]
+	case 2:
+		*(*uint16)(pointer) = order.Uint16(b)
+	case 4:
+		*(*uint32)(pointer) = order.Uint32(b)
+	case 8:
+		*(*uint64)(pointer) = order.Uint64(b)
+	}
+
+	return value
+}
+
+// PutSlice - записать элементы среза (или массива через arr[:]) подряд в b
+func PutSlice[T Number](order ByteOrder, b []byte, values []T) {
+	size := int(unsafe.Sizeof(*new(T)))
+	for i, value := range values {
+		Put(order, b[i*size:], value)
+	}
+}
+
+// GetSlice - прочитать len(values) элементов подряд из b
+func GetSlice[T Number](order ByteOrder, b []byte, values []T) {
+	size := int(unsafe.Sizeof(*new(T)))
+	for i := range values {
+		values[i] = Get[T](order, b[i*size:])
+	}
+}
+
+// ConvertSlice - на месте перевести элементы между порядком платформы и order
+// (операция симметрична, поэтому подходит для преобразования в обе стороны)
+func ConvertSlice[T Number](order ByteOrder, values []T) {
+	if order != HostOrder {
+		SwapSlice(values)
+	}
+}
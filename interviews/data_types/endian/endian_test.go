@@ -0,0 +1,103 @@
+package endian
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteOrder(t *testing.T) {
+	buffer := make([]byte, 8)
+
+	LittleEndian.PutUint16(buffer, 0x0102)
+	assert.Equal(t, []byte{0x02, 0x01}, buffer[:2])
+	assert.Equal(t, uint16(0x0102), LittleEndian.Uint16(buffer))
+	BigEndian.PutUint16(buffer, 0x0102)
+	assert.Equal(t, []byte{0x01, 0x02}, buffer[:2])
+	assert.Equal(t, uint16(0x0102), BigEndian.Uint16(buffer))
+
+	LittleEndian.PutUint32(buffer, 0x01020304)
+	assert.Equal(t, []byte{0x04, 0x03, 0x02, 0x01}, buffer[:4])
+	assert.Equal(t, uint32(0x01020304), LittleEndian.Uint32(buffer))
+	BigEndian.PutUint32(buffer, 0x01020304)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, buffer[:4])
+	assert.Equal(t, uint32(0x01020304), BigEndian.Uint32(buffer))
+
+	LittleEndian.PutUint64(buffer, 0x0102030405060708)
+	assert.Equal(t, []byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01}, buffer)
+	assert.Equal(t, uint64(0x0102030405060708), LittleEndian.Uint64(buffer))
+	BigEndian.PutUint64(buffer, 0x0102030405060708)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, buffer)
+	assert.Equal(t, uint64(0x0102030405060708), BigEndian.Uint64(buffer))
+}
+
+func TestHostOrder(t *testing.T) {
+	var number uint32 = 0x01020304
+	bytes := (*[4]byte)(unsafe.Pointer(&number))
+	assert.Equal(t, number, HostOrder.Uint32(bytes[:]))
+}
+
+func TestSwap(t *testing.T) {
+	assert.Equal(t, int8(-5), Swap(int8(-5)))
+	assert.Equal(t, int16(0x0201), Swap(int16(0x0102)))
+	assert.Equal(t, int32(-1), Swap(int32(-1)))
+	assert.Equal(t, uint64(0x0807060504030201), Swap(uint64(0x0102030405060708)))
+	assert.Equal(t, math.Pi, Swap(Swap(math.Pi)))
+	assert.Equal(t, math.Float32bits(1.5), Swap(Swap(math.Float32bits(1.5))))
+}
+
+func TestPutGet(t *testing.T) {
+	tests := map[string]struct {
+		order ByteOrder
+		bytes []byte
+	}{
+		"little endian": {
+			order: LittleEndian,
+			bytes: []byte{0x00, 0x00, 0xC0, 0x3F},
+		},
+		"big endian": {
+			order: BigEndian,
+			bytes: []byte{0x3F, 0xC0, 0x00, 0x00},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			buffer := make([]byte, 4)
+			Put(test.order, buffer, float32(1.5))
+			assert.Equal(t, test.bytes, buffer)
+			assert.Equal(t, float32(1.5), Get[float32](test.order, buffer))
+
+			Put(test.order, buffer, int32(-2))
+			assert.Equal(t, int32(-2), Get[int32](test.order, buffer))
+		})
+	}
+}
+
+func TestSlices(t *testing.T) {
+	array := [3]int16{1, -2, 0x0304}
+	buffer := make([]byte, 6)
+
+	PutSlice(BigEndian, buffer, array[:])
+	assert.Equal(t, []byte{0x00, 0x01, 0xFF, 0xFE, 0x03, 0x04}, buffer)
+
+	var decoded [3]int16
+	GetSlice(BigEndian, buffer, decoded[:])
+	assert.Equal(t, array, decoded)
+
+	values := []uint32{0x01020304, 0x0A0B0C0D}
+	ConvertSlice(HostOrder, values)
+	assert.Equal(t, []uint32{0x01020304, 0x0A0B0C0D}, values)
+
+	other := BigEndian
+	if HostOrder == BigEndian {
+		other = LittleEndian
+	}
+
+	ConvertSlice(other, values)
+	assert.Equal(t, []uint32{0x04030201, 0x0D0C0B0A}, values)
+	ConvertSlice(other, values)
+	assert.Equal(t, []uint32{0x01020304, 0x0A0B0C0D}, values)
+}
@@ -0,0 +1,89 @@
+package endian
+
+import (
+	"math/bits"
+	"unsafe"
+)
+
+const (
+	mask16   = 0x00FF00FF00FF00FF
+	wordSize = int(unsafe.Sizeof(uint64(0)))
+)
+
+// SwapSlice - на месте развернуть порядок байт каждого элемента среза
+func SwapSlice[T Number](values []T) {
+	if len(values) == 0 {
+		return
+	}
+
+	pointer := unsafe.Pointer(unsafe.SliceData(values))
+	switch unsafe.Sizeof(values[0]) {
+	case 2:
+		swapSlice16(unsafe.Slice((*uint16)(pointer), len(values)))
+	case 4:
+		swapSlice32(unsafe.Slice((*uint32)(pointer), len(values)))
+	case 8:
+		swapSlice64(unsafe.Slice((*uint64)(pointer), len(values)))
+	}
+}
+
+// swapSlice16 - обрабатывает по 4 элемента за раз в одном 64-битном слове
+func swapSlice16(values []uint16) {
+	head := alignedHead(unsafe.Pointer(unsafe.SliceData(values)), 2, len(values))
+	for i := 0; i < head; i++ {
+		values[i] = bits.ReverseBytes16(values[i])
+	}
+
+	values = values[head:]
+	words := len(values) / 4
+	if words > 0 {
+		wordSlice := unsafe.Slice((*uint64)(unsafe.Pointer(unsafe.SliceData(values))), words)
+		for i := range wordSlice {
+			word := wordSlice[i]
+			wordSlice[i] = (word&mask16)<<8 | (word>>8)&mask16
+		}
+	}
+
+	for i := words * 4; i < len(values); i++ {
+		values[i] = bits.ReverseBytes16(values[i])
+	}
+}
+
+// swapSlice32 - обрабатывает по 2 элемента за раз: разворот всего слова
+// меняет местами и половины, поэтому затем они возвращаются поворотом на 32 бита
+func swapSlice32(values []uint32) {
+	head := alignedHead(unsafe.Pointer(unsafe.SliceData(values)), 4, len(values))
+	for i := 0; i < head; i++ {
+		values[i] = bits.ReverseBytes32(values[i])
+	}
+
+	values = values[head:]
+	words := len(values) / 2
+	if words > 0 {
+		wordSlice := unsafe.Slice((*uint64)(unsafe.Pointer(unsafe.SliceData(values))), words)
+		for i := range wordSlice {
+			wordSlice[i] = bits.RotateLeft64(bits.ReverseBytes64(wordSlice[i]), 32)
+		}
+	}
+
+	for i := words * 2; i < len(values); i++ {
+		values[i] = bits.ReverseBytes32(values[i])
+	}
+}
+
+func swapSlice64(values []uint64) {
+	for i := range values {
+		values[i] = bits.ReverseBytes64(values[i])
+	}
+}
+
+// alignedHead - сколько элементов нужно обработать поштучно, чтобы
+// дальнейшие 64-битные слова были выровнены
+func alignedHead(pointer unsafe.Pointer, size, length int) int {
+	misalignment := int(uintptr(pointer) % uintptr(wordSize))
+	if misalignment == 0 {
+		return 0
+	}
+
+	return min((wordSize-misalignment)/size, length)
+}
@@ -0,0 +1,36 @@
+//go:build !debug
+
+package lockdebug
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Without the debug tag the wrappers are plain sync types with no overhead.
+// Build or test with -tags debug to enable profiling and deadlock detection.
+
+type Mutex = sync.Mutex
+type RWMutex = sync.RWMutex
+
+func Report(w io.Writer) {
+	fmt.Fprintln(w, "lock profiling is disabled, build with -tags debug")
+}
+
+// Stats, Inversions and Recursions have nothing to report,
+// they exist so that the code compiles the same way with and without the tag
+
+func Stats() []SiteStats {
+	return nil
+}
+
+func Inversions() []Inversion {
+	return nil
+}
+
+func Recursions() []Recursion {
+	return nil
+}
+
+func Reset() {}
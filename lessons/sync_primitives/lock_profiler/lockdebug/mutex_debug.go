@@ -0,0 +1,108 @@
+//go:build debug
+
+package lockdebug
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Mutex - sync.Mutex that reports its usage to the profiler
+type Mutex struct {
+	mutex sync.Mutex
+}
+
+func (m *Mutex) Lock() {
+	lock(m.mutex.TryLock, m.mutex.Lock, m, m.name(), writeMode, callSite(1))
+}
+
+func (m *Mutex) TryLock() bool {
+	return tryLock(m.mutex.TryLock, m, m.name(), writeMode, callSite(1))
+}
+
+func (m *Mutex) Unlock() {
+	global.release(goroutineID(), m, writeMode)
+	m.mutex.Unlock()
+}
+
+func (m *Mutex) name() string {
+	return fmt.Sprintf("Mutex(%p)", m)
+}
+
+// RWMutex - sync.RWMutex that reports its usage to the profiler
+type RWMutex struct {
+	mutex sync.RWMutex
+}
+
+func (m *RWMutex) Lock() {
+	lock(m.mutex.TryLock, m.mutex.Lock, m, m.name(), writeMode, callSite(1))
+}
+
+func (m *RWMutex) TryLock() bool {
+	return tryLock(m.mutex.TryLock, m, m.name(), writeMode, callSite(1))
+}
+
+func (m *RWMutex) Unlock() {
+	global.release(goroutineID(), m, writeMode)
+	m.mutex.Unlock()
+}
+
+func (m *RWMutex) RLock() {
+	m.rlock(callSite(1))
+}
+
+func (m *RWMutex) TryRLock() bool {
+	return tryLock(m.mutex.TryRLock, m, m.name(), readMode, callSite(1))
+}
+
+func (m *RWMutex) RUnlock() {
+	global.release(goroutineID(), m, readMode)
+	m.mutex.RUnlock()
+}
+
+func (m *RWMutex) RLocker() sync.Locker {
+	return (*rlocker)(m)
+}
+
+func (m *RWMutex) rlock(site string) {
+	lock(m.mutex.TryRLock, m.mutex.RLock, m, m.name(), readMode, site)
+}
+
+func (m *RWMutex) name() string {
+	return fmt.Sprintf("RWMutex(%p)", m)
+}
+
+type rlocker RWMutex
+
+func (r *rlocker) Lock() {
+	(*RWMutex)(r).rlock(callSite(1))
+}
+
+func (r *rlocker) Unlock() {
+	(*RWMutex)(r).RUnlock()
+}
+
+// lock - acquire is called only if try fails, which marks the acquisition contended
+func lock(try func() bool, acquire func(), key any, name string, mode lockMode, site string) {
+	goroutine := goroutineID()
+	global.beforeLock(goroutine, key, name, mode, site)
+
+	start := time.Now()
+	contended := !try()
+	if contended {
+		acquire()
+	}
+
+	global.afterLock(goroutine, key, name, mode, site, time.Since(start), contended)
+}
+
+// tryLock - a failed TryLock never blocks, so it can't deadlock and isn't recorded
+func tryLock(try func() bool, key any, name string, mode lockMode, site string) bool {
+	if !try() {
+		return false
+	}
+
+	global.afterLock(goroutineID(), key, name, mode, site, 0, false)
+	return true
+}
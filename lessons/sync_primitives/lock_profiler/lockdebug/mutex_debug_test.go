@@ -0,0 +1,150 @@
+//go:build debug
+
+package lockdebug
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -tags debug -race -v .
+
+func TestLockOrderInversion(t *testing.T) {
+	Reset()
+
+	var first, second, third Mutex
+	lockPair := func(lhs, rhs *Mutex) {
+		lhs.Lock()
+		rhs.Lock()
+		rhs.Unlock()
+		lhs.Unlock()
+	}
+
+	// goroutines run one after another, so there is no real deadlock
+	lockPair(&first, &second)
+	lockPair(&second, &third)
+	assert.Empty(t, Inversions())
+
+	lockPair(&first, &third) // consistent with first -> second -> third
+	assert.Empty(t, Inversions())
+
+	lockPair(&third, &first)
+	inversions := Inversions()
+	assert.Len(t, inversions, 1)
+	assert.Equal(t, third.name(), inversions[0].Edge.From)
+	assert.Equal(t, first.name(), inversions[0].Edge.To)
+	assert.Equal(t, first.name(), inversions[0].Reverse[0].From)
+	assert.Equal(t, third.name(), inversions[0].Reverse[len(inversions[0].Reverse)-1].To)
+
+	// the same order is reported once
+	lockPair(&third, &first)
+	assert.Len(t, Inversions(), 1)
+}
+
+func TestReadLocksOrder(t *testing.T) {
+	Reset()
+
+	var first, second RWMutex
+	rlockPair := func(lhs, rhs *RWMutex) {
+		lhs.RLock()
+		rhs.RLock()
+		rhs.RUnlock()
+		lhs.RUnlock()
+	}
+
+	// a writer queued on the second lock blocks the first goroutine's RLock,
+	// and a writer queued on the first one blocks the second goroutine's RLock
+	rlockPair(&first, &second)
+	assert.Empty(t, Inversions())
+	rlockPair(&second, &first)
+	assert.Len(t, Inversions(), 1)
+}
+
+func TestRecursiveLocking(t *testing.T) {
+	Reset()
+
+	var mutex Mutex
+	mutex.Lock()
+	assert.Panics(t, mutex.Lock) // instead of blocking forever
+	mutex.Unlock()
+
+	var rwMutex RWMutex
+	rwMutex.RLock()
+	assert.Panics(t, rwMutex.Lock)
+	assert.Panics(t, rwMutex.RLock)
+	rwMutex.RUnlock()
+
+	recursions := Recursions()
+	assert.Len(t, recursions, 3)
+	assert.Equal(t, mutex.name(), recursions[0].Lock)
+	assert.True(t, strings.HasPrefix(recursions[0].HeldAt, "mutex_debug_test.go:"))
+
+	// a failed TryLock doesn't block, so it isn't recursion
+	mutex.Lock()
+	assert.False(t, mutex.TryLock())
+	mutex.Unlock()
+	assert.Len(t, Recursions(), 3)
+}
+
+func TestContentionStats(t *testing.T) {
+	Reset()
+
+	var mutex RWMutex
+	mutex.Lock()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		locker := mutex.RLocker()
+		locker.Lock() // waits for the writer
+		locker.Unlock()
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	mutex.Unlock()
+	wg.Wait()
+
+	stats := Stats()
+	assert.Len(t, stats, 2)
+
+	reader := stats[0] // the most waited site goes first
+	assert.True(t, strings.HasPrefix(reader.Site, "mutex_debug_test.go:"))
+	assert.Equal(t, 1, reader.Acquisitions)
+	assert.Equal(t, 1, reader.Contended)
+	assert.GreaterOrEqual(t, reader.MaxWait, 25*time.Millisecond)
+
+	writer := stats[1]
+	assert.Zero(t, writer.Contended)
+	assert.GreaterOrEqual(t, writer.TotalHold, 50*time.Millisecond)
+
+	var report strings.Builder
+	Report(&report)
+	assert.Contains(t, report.String(), reader.Site)
+	assert.Contains(t, report.String(), writer.Site)
+}
+
+func TestUnlockByAnotherGoroutine(t *testing.T) {
+	Reset()
+
+	var mutex Mutex
+	mutex.Lock()
+
+	done := make(chan struct{})
+	go func() {
+		mutex.Unlock()
+		close(done)
+	}()
+	<-done
+
+	// the lock isn't considered held by this goroutine any more
+	assert.NotPanics(t, func() {
+		mutex.Lock()
+		mutex.Unlock()
+	})
+	assert.Empty(t, global.held)
+}
@@ -0,0 +1,26 @@
+//go:build !debug
+
+package lockdebug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -race -v .
+
+func TestDisabledProfiling(t *testing.T) {
+	var mutex Mutex
+	mutex.Lock()
+	mutex.Unlock()
+
+	assert.Empty(t, Stats())
+	assert.Empty(t, Inversions())
+	assert.Empty(t, Recursions())
+
+	var report strings.Builder
+	Report(&report)
+	assert.Contains(t, report.String(), "-tags debug")
+}
@@ -0,0 +1,269 @@
+//go:build debug
+
+package lockdebug
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+type lockMode int
+
+const (
+	writeMode lockMode = iota
+	readMode
+)
+
+type heldLock struct {
+	lock  any
+	name  string
+	mode  lockMode
+	site  string
+	since time.Time
+}
+
+type profiler struct {
+	mutex      sync.Mutex
+	sites      map[string]*SiteStats
+	held       map[uint64][]heldLock      // by goroutine id
+	order      map[string]map[string]Edge // lock-order graph by lock name: held -> acquired
+	inversions []Inversion
+	recursions []Recursion
+}
+
+var global = newProfiler()
+
+func newProfiler() *profiler {
+	return &profiler{
+		sites: make(map[string]*SiteStats),
+		held:  make(map[uint64][]heldLock),
+		order: make(map[string]map[string]Edge),
+	}
+}
+
+// beforeLock - is called before blocking, so a real deadlock is still reported.
+// Read locks are ordered too: a pending writer blocks new readers of sync.RWMutex,
+// so two goroutines read locking in opposite orders deadlock once writers queue.
+// The order graph is keyed by name rather than by the lock itself, so it doesn't
+// keep every mutex that was ever nested reachable; the name is the address, which
+// may be reused by a new mutex after the old one is collected, call Reset if needed
+func (p *profiler) beforeLock(goroutine uint64, lock any, name string, mode lockMode, site string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	held := p.held[goroutine]
+	for _, h := range held {
+		if h.lock == lock {
+			p.recursions = append(p.recursions, Recursion{Lock: name, HeldAt: h.site, AcquiredAt: site})
+			panic(fmt.Sprintf("lockdebug: recursive locking of %s at %s, already held since %s", name, site, h.site))
+		}
+	}
+
+	for _, h := range held {
+		if _, ok := p.order[h.name][name]; ok {
+			continue
+		}
+
+		edge := Edge{From: h.name, To: name, HeldAt: h.site, AcquiredAt: site}
+		if reverse := p.path(name, h.name, make(map[string]bool)); reverse != nil {
+			p.inversions = append(p.inversions, Inversion{Edge: edge, Reverse: reverse})
+		}
+
+		if p.order[h.name] == nil {
+			p.order[h.name] = make(map[string]Edge)
+		}
+		p.order[h.name][name] = edge
+	}
+}
+
+// path - edges of the lock-order graph leading from one lock to another
+func (p *profiler) path(from, to string, visited map[string]bool) []Edge {
+	visited[from] = true
+	for next, edge := range p.order[from] {
+		if next == to {
+			return []Edge{edge}
+		}
+
+		if !visited[next] {
+			if rest := p.path(next, to, visited); rest != nil {
+				return append([]Edge{edge}, rest...)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *profiler) afterLock(goroutine uint64, lock any, name string, mode lockMode, site string, wait time.Duration, contended bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := p.site(site)
+	stats.Acquisitions++
+	if contended {
+		stats.Contended++
+	}
+	stats.TotalWait += wait
+	stats.MaxWait = max(stats.MaxWait, wait)
+
+	held := heldLock{lock: lock, name: name, mode: mode, site: site, since: time.Now()}
+	p.held[goroutine] = append(p.held[goroutine], held)
+}
+
+// release - the lock may be released by another goroutine than the one that
+// acquired it, like sync.Mutex allows, so all goroutines are searched
+func (p *profiler) release(goroutine uint64, lock any, mode lockMode) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	owner, idx := goroutine, p.find(goroutine, lock, mode)
+	for other := range p.held {
+		if idx >= 0 {
+			break
+		}
+		owner, idx = other, p.find(other, lock, mode)
+	}
+	if idx < 0 {
+		return // unlock of unlocked lock, sync reports it itself
+	}
+
+	held := p.held[owner][idx]
+	hold := time.Since(held.since)
+	stats := p.site(held.site)
+	stats.TotalHold += hold
+	stats.MaxHold = max(stats.MaxHold, hold)
+
+	p.held[owner] = slices.Delete(p.held[owner], idx, idx+1)
+	if len(p.held[owner]) == 0 {
+		delete(p.held, owner)
+	}
+}
+
+func (p *profiler) find(goroutine uint64, lock any, mode lockMode) int {
+	held := p.held[goroutine]
+	for idx := len(held) - 1; idx >= 0; idx-- {
+		if held[idx].lock == lock && held[idx].mode == mode {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func (p *profiler) site(site string) *SiteStats {
+	stats, ok := p.sites[site]
+	if !ok {
+		stats = &SiteStats{Site: site}
+		p.sites[site] = stats
+	}
+
+	return stats
+}
+
+// Stats - statistics by call site, the most waited first
+func Stats() []SiteStats {
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+
+	stats := make([]SiteStats, 0, len(global.sites))
+	for _, site := range global.sites {
+		stats = append(stats, *site)
+	}
+
+	slices.SortFunc(stats, func(lhs, rhs SiteStats) int {
+		if lhs.TotalWait != rhs.TotalWait {
+			return cmp.Compare(rhs.TotalWait, lhs.TotalWait)
+		}
+		return strings.Compare(lhs.Site, rhs.Site)
+	})
+
+	return stats
+}
+
+func Inversions() []Inversion {
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+
+	return slices.Clone(global.inversions)
+}
+
+func Recursions() []Recursion {
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+
+	return slices.Clone(global.recursions)
+}
+
+// Reset - forget collected statistics, locks that are currently held stay tracked
+func Reset() {
+	global.mutex.Lock()
+	defer global.mutex.Unlock()
+
+	global.sites = make(map[string]*SiteStats)
+	global.order = make(map[string]map[string]Edge)
+	global.inversions = nil
+	global.recursions = nil
+}
+
+func Report(w io.Writer) {
+	fmt.Fprintln(w, "lock contention by call site:")
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "  site\tacquisitions\tcontended\ttotal wait\tmax wait\ttotal hold\tmax hold")
+	for _, s := range Stats() {
+		fmt.Fprintf(table, "  %s\t%d\t%d\t%v\t%v\t%v\t%v\n",
+			s.Site, s.Acquisitions, s.Contended, s.TotalWait, s.MaxWait, s.TotalHold, s.MaxHold)
+	}
+	table.Flush()
+
+	if inversions := Inversions(); len(inversions) != 0 {
+		fmt.Fprintln(w, "potential deadlocks (lock order inversions):")
+		for _, inversion := range inversions {
+			fmt.Fprintf(w, "  %s\n", formatEdge(inversion.Edge))
+			for _, edge := range inversion.Reverse {
+				fmt.Fprintf(w, "    reversed by %s\n", formatEdge(edge))
+			}
+		}
+	}
+
+	if recursions := Recursions(); len(recursions) != 0 {
+		fmt.Fprintln(w, "recursive locking:")
+		for _, recursion := range recursions {
+			fmt.Fprintf(w, "  %s locked at %s, already held since %s\n",
+				recursion.Lock, recursion.AcquiredAt, recursion.HeldAt)
+		}
+	}
+}
+
+func formatEdge(edge Edge) string {
+	return fmt.Sprintf("%s (held since %s) -> %s (acquired at %s)", edge.From, edge.HeldAt, edge.To, edge.AcquiredAt)
+}
+
+// goroutineID - parsed from the "goroutine N [running]:" stack header,
+// the runtime doesn't expose it on purpose, so it is only for debugging
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	header := bytes.TrimPrefix(buf[:n], []byte("goroutine "))
+	id, _ := strconv.ParseUint(string(header[:bytes.IndexByte(header, ' ')]), 10, 64)
+	return id
+}
+
+// callSite - file:line of the code calling the lock method
+func callSite(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "unknown"
+	}
+
+	return filepath.Base(file) + ":" + strconv.Itoa(line)
+}
@@ -0,0 +1,35 @@
+package lockdebug
+
+import "time"
+
+// SiteStats - contention of the locks acquired at one call site
+type SiteStats struct {
+	Site         string
+	Acquisitions int
+	Contended    int // acquisitions that had to wait for another holder
+	TotalWait    time.Duration
+	MaxWait      time.Duration
+	TotalHold    time.Duration
+	MaxHold      time.Duration
+}
+
+// Edge - Lock To was acquired at AcquiredAt while Lock From was held since HeldAt
+type Edge struct {
+	From, To           string
+	HeldAt, AcquiredAt string
+}
+
+// Inversion - Edge closes a cycle with the already observed Reverse path
+// from Edge.To back to Edge.From, so goroutines taking these paths
+// concurrently can deadlock
+type Inversion struct {
+	Edge    Edge
+	Reverse []Edge
+}
+
+// Recursion - the goroutine tried to acquire a lock it already holds
+type Recursion struct {
+	Lock       string
+	HeldAt     string
+	AcquiredAt string
+}
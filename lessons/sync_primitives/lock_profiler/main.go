@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"golang_course/lessons/sync_primitives/lock_profiler/lockdebug"
+)
+
+// go run -tags debug main.go
+
+func normalizeResources(lhs, rhs *lockdebug.Mutex) {
+	lhs.Lock()
+	rhs.Lock()
+
+	time.Sleep(time.Millisecond) // normalization
+
+	rhs.Unlock()
+	lhs.Unlock()
+}
+
+func main() {
+	var mutex1 lockdebug.Mutex
+	var mutex2 lockdebug.Mutex
+
+	wg := sync.WaitGroup{}
+	wg.Add(10)
+
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			normalizeResources(&mutex1, &mutex2)
+		}()
+	}
+
+	wg.Wait()
+
+	// the opposite order doesn't deadlock here, but it is reported
+	normalizeResources(&mutex2, &mutex1)
+
+	lockdebug.Report(os.Stdout)
+}
@@ -0,0 +1,122 @@
+package semaphore
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrWeight - a negative weight would lower the count, and a weight larger
+// than the size would block forever
+var ErrWeight = errors.New("semaphore: acquire weight out of range")
+
+// Semaphore - weighted semaphore, waiters are served in FIFO order,
+// so a large request is not starved by a stream of small ones
+type Semaphore struct {
+	mutex   sync.Mutex
+	size    int64
+	current int64
+	waiters list.List // of *waiter
+}
+
+type waiter struct {
+	weight int64
+	ready  chan struct{}
+}
+
+func NewSemaphore(size int64) *Semaphore {
+	return &Semaphore{
+		size: size,
+	}
+}
+
+func (s *Semaphore) Acquire(n int64) error {
+	return s.AcquireContext(context.Background(), n)
+}
+
+// AcquireContext - like Acquire, but gives up waiting and returns ctx.Err()
+// when ctx is done, the canceled waiter leaves the queue
+func (s *Semaphore) AcquireContext(ctx context.Context, n int64) error {
+	s.mutex.Lock()
+	if n < 0 || n > s.size {
+		s.mutex.Unlock()
+		return ErrWeight
+	}
+
+	if s.waiters.Len() == 0 && s.size-s.current >= n {
+		s.current += n
+		s.mutex.Unlock()
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		s.mutex.Unlock()
+		return err
+	}
+
+	w := &waiter{weight: n, ready: make(chan struct{})}
+	element := s.waiters.PushBack(w)
+	s.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		s.current -= n // acquired concurrently with cancellation, give it back
+	default:
+		s.waiters.Remove(element)
+	}
+
+	s.wakeWaiters() // the canceled head may have held back smaller requests
+	return ctx.Err()
+}
+
+// TryAcquire - acquire without waiting, fails if there are queued waiters
+// to keep FIFO order
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if n < 0 || s.waiters.Len() != 0 || s.size-s.current < n {
+		return false
+	}
+
+	s.current += n
+	return true
+}
+
+func (s *Semaphore) Release(n int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if n < 0 {
+		panic("semaphore: release weight is negative")
+	}
+	if n > s.current {
+		panic("semaphore: released more than held")
+	}
+
+	s.current -= n
+	s.wakeWaiters()
+}
+
+func (s *Semaphore) wakeWaiters() {
+	for element := s.waiters.Front(); element != nil; element = s.waiters.Front() {
+		waiter := element.Value.(*waiter)
+		if s.size-s.current < waiter.weight {
+			break // the head waits for enough space, the next ones wait behind it
+		}
+
+		s.current += waiter.weight
+		s.waiters.Remove(element)
+		close(waiter.ready)
+	}
+}
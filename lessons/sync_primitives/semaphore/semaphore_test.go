@@ -0,0 +1,175 @@
+package semaphore
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSemaphoreWeights(t *testing.T) {
+	semaphore := NewSemaphore(10)
+
+	assert.True(t, semaphore.TryAcquire(6))
+	assert.False(t, semaphore.TryAcquire(5))
+	assert.True(t, semaphore.TryAcquire(4))
+	assert.False(t, semaphore.TryAcquire(1))
+
+	semaphore.Release(10)
+	assert.PanicsWithValue(t, "semaphore: released more than held", func() { semaphore.Release(1) })
+	assert.PanicsWithValue(t, "semaphore: release weight is negative", func() { semaphore.Release(-1) })
+	assert.ErrorIs(t, semaphore.Acquire(11), ErrWeight)
+	assert.ErrorIs(t, semaphore.Acquire(-5), ErrWeight)
+	assert.False(t, semaphore.TryAcquire(-5))
+	assert.Zero(t, semaphore.current)
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	semaphore := NewSemaphore(10)
+	assert.NoError(t, semaphore.Acquire(8))
+
+	waiting := func() int {
+		semaphore.mutex.Lock()
+		defer semaphore.mutex.Unlock()
+		return semaphore.waiters.Len()
+	}
+
+	var wg sync.WaitGroup
+	for idx, weight := range []int64{6, 1, 1} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, semaphore.Acquire(weight))
+		}()
+
+		assert.Eventually(t, func() bool {
+			return waiting() == idx+1
+		}, time.Second, time.Millisecond)
+	}
+
+	// space for small requests is free, but they wait behind the large one
+	semaphore.Release(3)
+	assert.Equal(t, 3, waiting())
+	assert.False(t, semaphore.TryAcquire(1))
+
+	semaphore.Release(5)
+	wg.Wait()
+	assert.Zero(t, waiting())
+	assert.Equal(t, int64(8), semaphore.current)
+}
+
+func TestSemaphoreConcurrent(t *testing.T) {
+	const size = 100
+	semaphore := NewSemaphore(size)
+
+	var held, violations atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(weight int64) {
+			defer wg.Done()
+			assert.NoError(t, semaphore.Acquire(weight))
+			if held.Add(weight) > size {
+				violations.Add(1)
+			}
+			held.Add(-weight)
+			semaphore.Release(weight)
+		}(int64(i%size) + 1)
+	}
+
+	wg.Wait()
+	assert.Zero(t, violations.Load())
+	assert.Zero(t, semaphore.current)
+}
+
+func TestSemaphoreAcquireContext(t *testing.T) {
+	semaphore := NewSemaphore(10)
+	ctx := context.Background()
+
+	assert.NoError(t, semaphore.AcquireContext(ctx, 7))
+	assert.ErrorIs(t, semaphore.AcquireContext(ctx, 11), ErrWeight)
+	assert.ErrorIs(t, semaphore.AcquireContext(ctx, -5), ErrWeight)
+	assert.Equal(t, int64(7), semaphore.current)
+
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, semaphore.AcquireContext(timeout, 5), context.DeadlineExceeded)
+	assert.Zero(t, semaphore.waiters.Len()) // the canceled waiter left the queue
+	assert.Equal(t, int64(7), semaphore.current)
+
+	assert.NoError(t, semaphore.AcquireContext(ctx, 3))
+	semaphore.Release(10)
+}
+
+func TestSemaphoreCanceledHeadWakesOthers(t *testing.T) {
+	semaphore := NewSemaphore(10)
+	ctx := context.Background()
+	assert.NoError(t, semaphore.AcquireContext(ctx, 8))
+
+	// the large request at the head holds back the small one behind it
+	large, cancelLarge := context.WithCancel(ctx)
+	largeDone := make(chan error)
+	go func() {
+		largeDone <- semaphore.AcquireContext(large, 5)
+	}()
+
+	assert.Eventually(t, func() bool {
+		semaphore.mutex.Lock()
+		defer semaphore.mutex.Unlock()
+		return semaphore.waiters.Len() == 1
+	}, time.Second, time.Millisecond)
+
+	smallDone := make(chan error)
+	go func() {
+		smallDone <- semaphore.AcquireContext(ctx, 2)
+	}()
+
+	select {
+	case <-smallDone:
+		t.Fatal("small request overtook large one")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancelLarge()
+	assert.ErrorIs(t, <-largeDone, context.Canceled)
+	assert.NoError(t, <-smallDone)
+	assert.Equal(t, int64(10), semaphore.current)
+}
+
+func TestSemaphoreContextStress(t *testing.T) {
+	const goroutines = 16
+	const iterations = 500
+	const size = 10
+
+	semaphore := NewSemaphore(size)
+	var held, violations atomic.Int64
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				n := rand.Int64N(size) + 1
+				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(rand.IntN(50))*time.Microsecond)
+				if semaphore.AcquireContext(ctx, n) == nil {
+					if held.Add(n) > size {
+						violations.Add(1)
+					}
+					held.Add(-n)
+					semaphore.Release(n)
+				}
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Zero(t, violations.Load())
+	assert.Zero(t, semaphore.current)
+	assert.Zero(t, semaphore.waiters.Len())
+}